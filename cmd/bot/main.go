@@ -8,7 +8,13 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/l1ghthouse/northstar-bootstrap/src/image"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod/thunderstore"
 	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+	"github.com/l1ghthouse/northstar-bootstrap/src/override"
+	"github.com/l1ghthouse/northstar-bootstrap/src/preset"
+	"github.com/l1ghthouse/northstar-bootstrap/src/sshkey"
 	"github.com/l1ghthouse/northstar-bootstrap/src/storage"
 	"github.com/l1ghthouse/northstar-bootstrap/src/storage/orm"
 
@@ -16,6 +22,7 @@ import (
 	"github.com/l1ghthouse/northstar-bootstrap/src/bot"
 	"github.com/l1ghthouse/northstar-bootstrap/src/config"
 	"github.com/l1ghthouse/northstar-bootstrap/src/providers"
+	"github.com/l1ghthouse/northstar-bootstrap/src/providers/util"
 )
 
 // nolint: cyclop
@@ -28,14 +35,17 @@ func main() {
 
 	rand.Seed(time.Now().UnixNano())
 
-	newBot, err := bot.NewBot(cfg.Bot)
+	thunderstore.Configure(cfg.Thunderstore)
+	util.ConfigureVersions(cfg.Versions)
+
+	err = mod.Load(cfg.Mods)
 	if err != nil {
-		log.Fatal("Failed to create bot: ", err)
+		log.Fatal("Failed to load mods: ", err)
 	}
 
-	provider, err := providers.NewProvider(cfg.Provider)
+	newBot, err := bot.NewBot(cfg.Bot)
 	if err != nil {
-		log.Fatal("Failed to create provider: ", err)
+		log.Fatal("Failed to create bot: ", err)
 	}
 
 	database, err := storage.NewDB(cfg.DB)
@@ -43,12 +53,21 @@ func main() {
 		log.Fatal("Failed to create db: ", err)
 	}
 
-	err = database.AutoMigrate(&nsserver.NSServer{})
+	err = database.AutoMigrate(&nsserver.NSServer{}, &preset.Preset{}, &override.Override{}, &image.Image{}, &sshkey.SSHKey{})
 	if err != nil {
 		log.Fatal("Failed to migrate db: ", err)
 	}
 
 	nsRepo := orm.NewNSServerRepo(database)
+	presetRepo := orm.NewPresetRepo(database)
+	overrideRepo := orm.NewOverrideRepo(database)
+	imageRepo := orm.NewImageRepo(database)
+	sshKeyRepo := orm.NewSSHKeyRepo(database)
+
+	provider, err := providers.NewProvider(cfg.Provider, nsRepo, imageRepo, sshKeyRepo)
+	if err != nil {
+		log.Fatal("Failed to create provider: ", err)
+	}
 
 	var autoDeleteDuration time.Duration
 	if cfg.MaxLifetimeSeconds != 0 {
@@ -72,7 +91,7 @@ func main() {
 		maxExtendDuration = time.Duration(cfg.MaxServerExtendDurationSeconds) * time.Second
 	}
 
-	autoDeleteManager, err := newBot.Start(provider, nsRepo, cfg.MaxConcurrentInstances, maxServerRate, autoDeleteDuration, maxExtendDuration)
+	autoDeleteManager, err := newBot.Start(provider, nsRepo, presetRepo, overrideRepo, cfg.MaxConcurrentInstances, maxServerRate, autoDeleteDuration, maxExtendDuration)
 	if err != nil {
 		log.Fatal("Error starting the bot: ", err)
 	}
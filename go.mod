@@ -3,6 +3,7 @@ module github.com/l1ghthouse/northstar-bootstrap
 go 1.17
 
 require (
+	al.essio.dev/pkg/shellescape v1.5.0
 	github.com/bramvdbogaerde/go-scp v1.2.0
 	github.com/bwmarrin/discordgo v0.25.0
 	github.com/gofrs/uuid v4.2.0+incompatible
@@ -16,13 +17,13 @@ require (
 	go.mongodb.org/mongo-driver v1.10.2
 	golang.org/x/crypto v0.0.0-20220622213112-05595931fe9d
 	golang.org/x/oauth2 v0.0.0-20211104180415-d3ed0bb246c8
+	gopkg.in/yaml.v2 v2.2.3
 	gorm.io/datatypes v1.0.5
 	gorm.io/driver/sqlite v1.2.6
 	gorm.io/gorm v1.22.5
 )
 
 require (
-	al.essio.dev/pkg/shellescape v1.5.0 // indirect
 	github.com/BurntSushi/toml v0.3.1 // indirect
 	github.com/go-sql-driver/mysql v1.6.0 // indirect
 	github.com/golang/protobuf v1.4.2 // indirect
@@ -47,7 +48,6 @@ require (
 	golang.org/x/text v0.3.7 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/protobuf v1.25.0 // indirect
-	gopkg.in/yaml.v2 v2.2.3 // indirect
 	gorm.io/driver/mysql v1.2.2 // indirect
 	gorm.io/driver/postgres v1.2.3 // indirect
 	gorm.io/driver/sqlserver v1.2.1 // indirect
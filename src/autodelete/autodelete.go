@@ -17,6 +17,7 @@ type Manager struct {
 	provider    providers.Provider
 	maxLifetime time.Duration
 	repo        nsserver.Repo
+	tickHooks   []func(ctx context.Context)
 }
 
 // NewAutoDeleteManager creates a new auto delete manager
@@ -29,6 +30,11 @@ func NewAutoDeleteManager(repo nsserver.Repo, provider providers.Provider, notif
 	}
 }
 
+// OnTick registers a hook, that is called after every auto delete check
+func (d *Manager) OnTick(hook func(ctx context.Context)) {
+	d.tickHooks = append(d.tickHooks, hook)
+}
+
 func (d *Manager) AutoDelete() {
 	ticker := time.NewTicker(time.Minute * 2)
 	ctx := context.Background()
@@ -59,6 +65,10 @@ func (d *Manager) AutoDelete() {
 				d.deleteAndNotify(ctx, server)
 			}
 		}
+
+		for _, hook := range d.tickHooks {
+			hook(ctx)
+		}
 	}
 }
 
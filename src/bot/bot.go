@@ -7,13 +7,15 @@ import (
 	"github.com/l1ghthouse/northstar-bootstrap/src/autodelete"
 
 	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+	"github.com/l1ghthouse/northstar-bootstrap/src/override"
+	"github.com/l1ghthouse/northstar-bootstrap/src/preset"
 
 	"github.com/l1ghthouse/northstar-bootstrap/src/bot/discord"
 	"github.com/l1ghthouse/northstar-bootstrap/src/providers"
 )
 
 type Bot interface {
-	Start(provider providers.Provider, repo nsserver.Repo, maxConcurrentServers uint, MaxServersPerHour uint, autoDeleteDuration time.Duration, maxExtendDuration time.Duration) (*autodelete.Manager, error)
+	Start(provider providers.Provider, repo nsserver.Repo, presetRepo preset.Repo, overrideRepo override.Repo, maxConcurrentServers uint, MaxServersPerHour uint, autoDeleteDuration time.Duration, maxExtendDuration time.Duration) (*autodelete.Manager, error)
 	Stop()
 }
 
@@ -0,0 +1,277 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod/thunderstore"
+	"github.com/l1ghthouse/northstar-bootstrap/src/providers"
+)
+
+// maxAutocompleteChoices is the maximum number of choices discord accepts in autocomplete response
+const maxAutocompleteChoices = 25
+
+// maxChoiceLength is the maximum length of choice name, and value
+const maxChoiceLength = 100
+
+// autocompleteFetchTimeout bounds fetches of cached values, that run in the background
+const autocompleteFetchTimeout = 30 * time.Second
+
+// autocomplete has to respond within 3 seconds, so values that are slow to fetch are cached, and fetched in the
+// background
+type cachedValues struct {
+	lock      *sync.Mutex
+	ttl       time.Duration
+	fetch     func(ctx context.Context) ([]string, error)
+	values    []string
+	fetchedAt time.Time
+	// refreshing is true, while the values are fetched
+	refreshing bool
+}
+
+func newCachedValues(ttl time.Duration, fetch func(ctx context.Context) ([]string, error)) *cachedValues {
+	return &cachedValues{
+		lock:  &sync.Mutex{},
+		ttl:   ttl,
+		fetch: fetch,
+	}
+}
+
+// get returns the cached values without waiting for them to be fetched. Expired values are returned, while they are
+// refreshed in the background, and nil is returned until the values are fetched for the first time
+func (c *cachedValues) get() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.values == nil || time.Since(c.fetchedAt) >= c.ttl {
+		c.refreshLocked()
+	}
+	return c.values
+}
+
+// refresh fetches the values in the background, unless they are already being fetched
+func (c *cachedValues) refresh() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.refreshLocked()
+}
+
+func (c *cachedValues) refreshLocked() {
+	if c.refreshing {
+		return
+	}
+	c.refreshing = true
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), autocompleteFetchTimeout)
+		defer cancel()
+		values, err := c.fetch(ctx)
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		c.refreshing = false
+		if err != nil {
+			log.Println(fmt.Sprintf("unable to refresh autocomplete values: %v", err))
+			return
+		}
+		c.values = values
+		c.fetchedAt = time.Now()
+	}()
+}
+
+type autocompleter struct {
+	regions              *cachedValues
+	thunderstorePackages *cachedValues
+}
+
+func newAutocompleter(provider providers.Provider) *autocompleter {
+	return &autocompleter{
+		regions: newCachedValues(time.Hour, provider.ListRegions),
+		thunderstorePackages: newCachedValues(30*time.Minute, func(ctx context.Context) ([]string, error) {
+			packages, err := thunderstore.GetPackages(ctx)
+			if err != nil {
+				return nil, err
+			}
+			names := make([]string, 0, len(packages))
+			for _, pkg := range packages {
+				if !pkg.IsDeprecated {
+					names = append(names, pkg.Owner+"/"+pkg.Name)
+				}
+			}
+			sort.Strings(names)
+			return names, nil
+		}),
+	}
+}
+
+// warm fetches the cached values in the background, so they are ready for the first autocomplete
+func (a *autocompleter) warm() {
+	a.regions.refresh()
+	a.thunderstorePackages.refresh()
+}
+
+func focusedOption(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, option := range options {
+		if option.Type == discordgo.ApplicationCommandOptionSubCommand {
+			return focusedOption(option.Options)
+		}
+		if option.Focused {
+			return option
+		}
+	}
+	return nil
+}
+
+// matchChoices returns values containing the typed text, values that start with it first
+func matchChoices(values []string, typed string) []string {
+	typed = strings.ToLower(strings.TrimSpace(typed))
+	var prefixed []string
+	var contained []string
+	for _, value := range values {
+		lower := strings.ToLower(value)
+		switch {
+		case strings.HasPrefix(lower, typed):
+			prefixed = append(prefixed, value)
+		case strings.Contains(lower, typed):
+			contained = append(contained, value)
+		}
+	}
+	matches := make([]string, 0, len(prefixed)+len(contained))
+	matches = append(matches, prefixed...)
+	matches = append(matches, contained...)
+	if len(matches) > maxAutocompleteChoices {
+		matches = matches[:maxAutocompleteChoices]
+	}
+	return matches
+}
+
+// matchListChoices completes the last element of comma separated list, keeping already typed elements
+func matchListChoices(values []string, typed string) []string {
+	var prefix string
+	last := typed
+	if idx := strings.LastIndex(typed, ","); idx != -1 {
+		prefix = typed[:idx+1]
+		last = typed[idx+1:]
+	}
+
+	matches := matchChoices(values, last)
+	choices := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if choice := prefix + match; len(choice) <= maxChoiceLength {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+// matchModListChoices completes the last element of the mods list, keeping the - prefix of disabled mods
+func matchModListChoices(typed string) []string {
+	var prefix string
+	last := typed
+	if idx := strings.LastIndex(typed, ","); idx != -1 {
+		prefix = typed[:idx+1]
+		last = typed[idx+1:]
+	}
+	if trimmed := strings.TrimSpace(last); strings.HasPrefix(trimmed, "-") {
+		prefix += "-"
+		last = strings.TrimPrefix(trimmed, "-")
+	}
+
+	matches := matchChoices(mod.Names(), last)
+	choices := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if choice := prefix + match; len(choice) <= maxChoiceLength {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+func (h *handler) serverNameChoices(ctx context.Context, guildID string) ([]string, error) {
+	cachedServers, err := h.nsRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(cachedServers))
+	for _, server := range cachedServers {
+		if h.ownsServer(guildID, server) {
+			names = append(names, server.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (h *handler) presetNameChoices(ctx context.Context, interaction *discordgo.InteractionCreate) ([]string, error) {
+	presets, err := h.presetRepo.List(ctx, interaction.GuildID, interaction.Member.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(presets))
+	for _, p := range presets {
+		names = append(names, p.Name)
+	}
+	return names, nil
+}
+
+func (h *handler) handleAutocomplete(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	ctx := context.Background()
+	data := interaction.ApplicationCommandData()
+	focused := focusedOption(data.Options)
+	if focused == nil {
+		return
+	}
+	typed := focused.StringValue()
+
+	var choices []string
+	var err error
+	switch {
+	case focused.Name == CreateServerPreset, data.Name == Preset && focused.Name == PresetName:
+		var names []string
+		names, err = h.presetNameChoices(ctx, interaction)
+		choices = matchChoices(names, typed)
+	case focused.Name == ServerNameOpt:
+		var names []string
+		names, err = h.serverNameChoices(ctx, interaction.GuildID)
+		choices = matchChoices(names, typed)
+	case focused.Name == CreateServerRegion:
+		choices = matchChoices(h.autocompleter.regions.get(), typed)
+	case data.Name == ModInfo && focused.Name == ModInfoName:
+		choices = matchChoices(mod.Names(), typed)
+	case focused.Name == PresetMods:
+		choices = matchModListChoices(typed)
+	case focused.Name == CreateServerPlaylist:
+		choices = matchChoices(mod.KnownGameContent().Playlists, typed)
+	case focused.Name == CreateServerMap:
+		choices = matchChoices(mod.KnownGameContent().Maps, typed)
+	case focused.Name == CreateServerMode:
+		choices = matchChoices(mod.KnownGameContent().Modes, typed)
+	case focused.Name == CreateServerCustomThunderstoreMods:
+		choices = matchListChoices(h.autocompleter.thunderstorePackages.get(), typed)
+	}
+	if err != nil {
+		log.Println(fmt.Sprintf("unable to autocomplete %s: %v", focused.Name, err))
+	}
+
+	response := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(choices))
+	for _, choice := range choices {
+		response = append(response, &discordgo.ApplicationCommandOptionChoice{
+			Name:  choice,
+			Value: choice,
+		})
+	}
+
+	if err := session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: response,
+		},
+	}); err != nil {
+		log.Println("Error responding to autocomplete: ", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,7 +21,9 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+	"github.com/l1ghthouse/northstar-bootstrap/src/preset"
 	"github.com/l1ghthouse/northstar-bootstrap/src/providers"
+	"gorm.io/datatypes"
 )
 
 const (
@@ -31,29 +34,224 @@ const (
 	RestartServer        = "restart_server"
 	ServerMetadata       = "server_metadata"
 	CommandFlagOverrides = "list_command_flag_overrides"
+	Preset               = "preset"
+	Overrides            = "overrides"
 )
 
-func modApplicationCommand() (options []*discordgo.ApplicationCommandOption) {
-	for k := range mod.ByName {
-		options = append(options, &discordgo.ApplicationCommandOption{
+func createServerOptions() []*discordgo.ApplicationCommandOption {
+	options := []*discordgo.ApplicationCommandOption{
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         CreateServerRegion,
+			Description:  "region in which the server will be created. Required, unless the preset specifies it",
+			Autocomplete: true,
+		},
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         CreateServerPreset,
+			Description:  "Saved preset to create the server from. Explicitly passed options override the preset",
+			Autocomplete: true,
+		},
+		{
 			Type:        discordgo.ApplicationCommandOptionBoolean,
-			Name:        k,
-			Description: fmt.Sprintf("Indicated that the server should include %s mod", k),
-		})
+			Name:        CreateServerOptInsecure,
+			Description: "Whether the server should be created with insecure mode(exposes IP address)",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        CreateServerOptMasterServer,
+			Description: "Custom Master Server",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        CreateServerVersionOpt,
+			Description: "Version of the server to create. If not specified, the latest version will be used",
+			Choices:     serverCreateVersionChoices(),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        CreateServerCustomDockerContainerOpt,
+			Description: "The Custom Docker Container must be under ghcr.io/pg9182/. Format: NAME:TAG",
+		},
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         PresetMods,
+			Description:  "Comma separated list of mods to enable. Prefix the mod with - to disable it. Ex: titan_debug,-pg9182_metrics",
+			Autocomplete: true,
+		},
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         CreateServerCustomThunderstoreMods,
+			Description:  "Comma separated list of custom thunderstore mods to install. Pin versions with Owner/Name@1.2.3",
+			Autocomplete: true,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        CreateServerModConfig,
+			Description: "Mod ConVars to set, comma separated. Ex: titan_debug.ConVarName=1",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        CreateServerCustomGithubBranch,
+			Description: "Branch of a NorthstarMods fork to test, as Owner/Repo@branch. Replaces the core mods of the server",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        CreateServerTickRate,
+			Description: "Custom TickRate to use for the server",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        CreateServerOptBareMetal,
+			Description: "Whether the server should be created on bare metal.",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        CreateServerOptCheatsEnabled,
+			Description: "Whether the server should be created with cheats enabled.",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        AdditionalExtraArgs,
+			Description: "Additional extra args to pass to the server",
+		},
 	}
-	return
+	options = append(options, gameApplicationOptions()...)
+	options = append(options, identityApplicationOptions()...)
+	options = append(options, &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        CreateServerPassword,
+		Description: "Password of the server. A random pin is generated, when not specified",
+	})
+	// mods aren't options of their own, as they don't fit into the command. They are enabled with the mods option,
+	// presets, and overrides instead
+	return options
 }
 
+// gameApplicationOptions returns options selecting what is played on the server
+func gameApplicationOptions() []*discordgo.ApplicationCommandOption {
+	// discord rejects explicitly passed values out of the range. Values of presets, and overrides are validated
+	// against the same range by optionInRange
+	minValue := float64(1)
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         CreateServerPlaylist,
+			Description:  "Playlist of the server. Ex: private_match, aitdm",
+			Autocomplete: true,
+		},
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         CreateServerMap,
+			Description:  "Map the server starts on. Ex: mp_glitch",
+			Autocomplete: true,
+		},
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         CreateServerMode,
+			Description:  "Game mode of the server. Ex: ctf",
+			Autocomplete: true,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        CreateServerMaxPlayers,
+			Description: fmt.Sprintf("Maximum number of players, up to %d", maxPlayersLimit),
+			MinValue:    &minValue,
+			MaxValue:    maxPlayersLimit,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        CreateServerScoreLimit,
+			Description: fmt.Sprintf("Score needed to win a match, up to %d", maxScoreLimit),
+			MinValue:    &minValue,
+			MaxValue:    maxScoreLimit,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        CreateServerTimeLimit,
+			Description: fmt.Sprintf("Time limit of a match in minutes, up to %d", maxTimeLimit),
+			MinValue:    &minValue,
+			MaxValue:    maxTimeLimit,
+		},
+	}
+}
+
+// identityApplicationOptions returns options changing how the server is listed in the server browser
+func identityApplicationOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        CreateServerDisplayName,
+			Description: fmt.Sprintf("Name listed in the server browser, up to %d characters. Defaults to [region]name", maxServerNameLength),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        CreateServerDescription,
+			Description: fmt.Sprintf("Description listed in the server browser, up to %d characters", maxServerDescriptionLength),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        CreateServerPublic,
+			Description: "Whether the server should have no password. Only permitted to some roles",
+		},
+	}
+}
+
+// maxPlayersLimit is the most players the dedicated server supports
+const maxPlayersLimit = 32
+
+// maxScoreLimit, and maxTimeLimit bound the limits of a match, so a match ends eventually
+const maxScoreLimit = 10000
+const maxTimeLimit = 180
+
+var ErrOptionOutOfRange = errors.New("option is out of range")
+
+// optionInRange checks, that the value is within the range of the numeric option
+func optionInRange(option *discordgo.ApplicationCommandOption, value float64) error {
+	if option.MinValue != nil && value < *option.MinValue {
+		return fmt.Errorf("%w: %s must be at least %v", ErrOptionOutOfRange, option.Name, *option.MinValue)
+	}
+	if option.MaxValue != 0 && value > option.MaxValue {
+		return fmt.Errorf("%w: %s must be at most %v", ErrOptionOutOfRange, option.Name, option.MaxValue)
+	}
+	return nil
+}
+
+// validateGameLimit checks the value of an integer option returned by gameApplicationOptions
+func validateGameLimit(name string, value uint64) error {
+	for _, option := range gameApplicationOptions() {
+		if option.Name == name {
+			return optionInRange(option, float64(value))
+		}
+	}
+	return nil
+}
+
+// maxCommandChoices is the maximum number of choices discord accepts for a single option
+const maxCommandChoices = 25
+
+// serverCreateVersionChoices offers the newest versions. Choices are registered with the command, so commands are
+// registered again, when the versions change
 func serverCreateVersionChoices() (options []*discordgo.ApplicationCommandOptionChoice) {
-	for k, v := range util.NorthstarVersions {
+	for _, v := range util.NorthstarVersions() {
+		if len(options) == maxCommandChoices {
+			break
+		}
+		name := v.Name
+		if v.IsLatest {
+			name += " (latest)"
+		}
 		options = append(options, &discordgo.ApplicationCommandOptionChoice{
 			Value: v.DockerImage,
-			Name:  k,
+			Name:  name,
 		})
 	}
 	return
 }
 
+const ServerNameOpt = "name"
+const CreateServerRegion = "region"
+const CreateServerPreset = "preset"
 const CreateServerOptInsecure = "insecure"
 const CreateServerOptMasterServer = "master_server"
 const CreateServerOptBareMetal = "bare_metal"
@@ -61,80 +259,53 @@ const CreateServerOptCheatsEnabled = "cheats_enabled"
 const CreateServerVersionOpt = "server_version"
 const CreateServerCustomDockerContainerOpt = "custom_container"
 const CreateServerCustomThunderstoreMods = "custom_thunderstore_mods"
+const CreateServerCustomGithubBranch = "custom_github_branch"
+const CreateServerModConfig = "mod_config"
 const CreateServerTickRate = "tick_rate"
+const CreateServerPlaylist = "playlist"
+const CreateServerMap = "map"
+const CreateServerMode = "mode"
+const CreateServerMaxPlayers = "max_players"
+const CreateServerScoreLimit = "score_limit"
+const CreateServerTimeLimit = "time_limit"
+const CreateServerDisplayName = "server_name"
+const CreateServerDescription = "description"
+const CreateServerPassword = "password"
+const CreateServerPublic = "public"
 const ListServerVerbosityOpt = "verbosity"
 const AdditionalExtraArgs = "additional_extra_args"
 const ExtendLifetime = "extend_lifetime"
-
-var (
-	commands = []*discordgo.ApplicationCommand{
+const PresetSave = "save"
+const PresetList = "list"
+const PresetDelete = "delete"
+const PresetName = "name"
+const PresetScope = "scope"
+const PresetMods = "mods"
+const OverridesSet = "set"
+const OverridesUnset = "unset"
+const OverridesCommand = "command"
+const OverridesFlag = "flag"
+const OverridesValue = "value"
+
+// applicationCommands returns commands of the bot. Commands are generated on every call, since create_server
+// options depend on the loaded mods.
+func applicationCommands() []*discordgo.ApplicationCommand {
+	return []*discordgo.ApplicationCommand{
 		{
 			Name:        CreateServer,
 			Description: "Command to create a server",
-			Options: append([]*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "region",
-					Description: "region in which the server will be created",
-					Required:    true,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionBoolean,
-					Name:        CreateServerOptInsecure,
-					Description: "Whether the server should be created with insecure mode(exposes IP address)",
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        CreateServerOptMasterServer,
-					Description: "Custom Master Server",
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        CreateServerVersionOpt,
-					Description: "Version of the server to create. If not specified, the latest version will be used",
-					Choices:     serverCreateVersionChoices(),
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        CreateServerCustomDockerContainerOpt,
-					Description: "The Custom Docker Container must be under ghcr.io/pg9182/. Format: NAME:TAG",
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        CreateServerCustomThunderstoreMods,
-					Description: "Comma separated list of custom thunderstore mods for server to install",
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        CreateServerTickRate,
-					Description: "Custom TickRate to use for the server",
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionBoolean,
-					Name:        CreateServerOptBareMetal,
-					Description: "Whether the server should be created on bare metal.",
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionBoolean,
-					Name:        CreateServerOptCheatsEnabled,
-					Description: "Whether the server should be created with cheats enabled.",
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        AdditionalExtraArgs,
-					Description: "Additional extra args to pass to the server",
-				},
-			}, modApplicationCommand()...),
+			Options:     createServerOptions(),
 		},
 		{
 			Name:        DeleteServer,
 			Description: "Command to delete a server",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "name",
-					Description: "server name to delete",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         ServerNameOpt,
+					Description:  "server name to delete",
+					Required:     true,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -143,10 +314,11 @@ var (
 			Description: "Command to restart the server",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "name",
-					Description: "server name to restart",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         ServerNameOpt,
+					Description:  "server name to restart",
+					Required:     true,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -155,10 +327,11 @@ var (
 			Description: "Command to extract logs from a server",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "name",
-					Description: "server name from which logs are to be extracted",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         ServerNameOpt,
+					Description:  "server name from which logs are to be extracted",
+					Required:     true,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -166,6 +339,56 @@ var (
 			Name:        CommandFlagOverrides,
 			Description: "Command flag overrides for current discord server",
 		},
+		{
+			Name:        Overrides,
+			Description: "Change command flag overrides for current discord server",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        OverridesSet,
+					Description: "Set the value a flag defaults to, when it's not passed explicitly",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        OverridesCommand,
+							Description: "command name, without the /",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        OverridesFlag,
+							Description: "flag of the command",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        OverridesValue,
+							Description: "value of the flag",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        OverridesUnset,
+					Description: "Remove the override set for a flag",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        OverridesCommand,
+							Description: "command name, without the /",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        OverridesFlag,
+							Description: "flag of the command",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
 		{
 			Name:        ListServer,
 			Description: "Command to list servers",
@@ -183,10 +406,101 @@ var (
 			Description: "Metadata associated with the server",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "name",
-					Description: "server name associated with metadata",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         ServerNameOpt,
+					Description:  "server name associated with metadata",
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        Preset,
+			Description: "Manage saved create_server presets",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        PresetSave,
+					Description: "Save a preset. Saving with an existing name replaces the preset",
+					Options: append([]*discordgo.ApplicationCommandOption{
+						{
+							Type:         discordgo.ApplicationCommandOptionString,
+							Name:         PresetName,
+							Description:  "name of the preset",
+							Required:     true,
+							Autocomplete: true,
+						},
+						presetScopeOption(),
+						{
+							Type:         discordgo.ApplicationCommandOptionString,
+							Name:         CreateServerRegion,
+							Description:  "region in which the server will be created",
+							Autocomplete: true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        CreateServerVersionOpt,
+							Description: "Version of the server to create",
+							Choices:     serverCreateVersionChoices(),
+						},
+						{
+							Type:         discordgo.ApplicationCommandOptionString,
+							Name:         PresetMods,
+							Description:  "Comma separated list of mods to enable. Prefix the mod with - to disable it. Ex: titan_debug,-pg9182_metrics",
+							Autocomplete: true,
+						},
+						{
+							Type:         discordgo.ApplicationCommandOptionString,
+							Name:         CreateServerCustomThunderstoreMods,
+							Description:  "Comma separated list of custom thunderstore mods to install. Pin versions with Owner/Name@1.2.3",
+							Autocomplete: true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        CreateServerModConfig,
+							Description: "Mod ConVars to set, comma separated. Ex: titan_debug.ConVarName=1",
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        CreateServerTickRate,
+							Description: "Custom TickRate to use for the server",
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        CreateServerOptCheatsEnabled,
+							Description: "Whether the server should be created with cheats enabled.",
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        CreateServerOptMasterServer,
+							Description: "Custom Master Server",
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        AdditionalExtraArgs,
+							Description: "Additional extra args to pass to the server",
+						},
+					}, append(gameApplicationOptions(), identityApplicationOptions()...)...),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        PresetList,
+					Description: "List presets available to you",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        PresetDelete,
+					Description: "Delete a preset",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:         discordgo.ApplicationCommandOptionString,
+							Name:         PresetName,
+							Description:  "name of the preset",
+							Required:     true,
+							Autocomplete: true,
+						},
+						presetScopeOption(),
+					},
 				},
 			},
 		},
@@ -195,10 +509,11 @@ var (
 			Description: "Extends lifetime of the server by a given amount. Ex: 1h, 30m, 1h30m50s",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "name",
-					Description: "server name associated with metadata",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         ServerNameOpt,
+					Description:  "server name associated with metadata",
+					Required:     true,
+					Autocomplete: true,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
@@ -208,20 +523,27 @@ var (
 				},
 			},
 		},
+		searchModsCommand(),
+		clientProfileCommand(),
+		modInfoCommand(),
+		buildImageCommand(),
 	}
-)
+}
 
 type handler struct {
 	p                    providers.Provider
 	maxConcurrentServers uint
 	autoDeleteDuration   time.Duration
 	nsRepo               nsserver.Repo
-	maxExtendDuration    time.Duration
 	maxServerCreateRate  uint
 	rateCounter          *ratecounter.RateCounter
 	createLock           *sync.Mutex
-	CommandOverrides     []CommandOverrides
 	notifier             *Notifier
+	presetRepo           preset.Repo
+	guilds               map[string]*guild
+	defaultGuildID       string
+	autocompleter        *autocompleter
+	statusBoard          *statusBoard
 }
 
 const unknown = "unknown"
@@ -236,59 +558,189 @@ func optionValue(options []*discordgo.ApplicationCommandInteractionDataOption, n
 	return nil, false
 }
 
-func (h *handler) extractCustomMods(interaction *discordgo.InteractionCreate) string {
-	thunderstoreMods := ""
-	val, ok := optionValue(interaction.ApplicationCommandData().Options, CreateServerCustomThunderstoreMods)
-	if ok {
-		thunderstoreMods = val.StringValue()
-	} else {
-		val, ok := h.getGlobalOverrideStringValue(interaction.ApplicationCommandData().Name, CreateServerCustomThunderstoreMods)
-		if ok {
-			thunderstoreMods = val
+// flagResolver looks up create_server flags. Explicitly passed options take precedence over the selected preset,
+// which in turn takes precedence over the command flag overrides.
+type flagResolver struct {
+	h       *handler
+	guildID string
+	command string
+	options []*discordgo.ApplicationCommandInteractionDataOption
+	preset  *preset.Preset
+	// mods are the ones explicitly passed with the mods option
+	mods map[string]bool
+}
+
+// modValue looks up whether the mod is enabled. The mods option takes precedence over presets, and overrides.
+func (r flagResolver) modValue(name string) (bool, bool) {
+	if val, ok := r.mods[name]; ok {
+		return val, true
+	}
+	return r.boolValue(name)
+}
+
+func (r flagResolver) boolValue(name string) (bool, bool) {
+	if val, ok := optionValue(r.options, name); ok {
+		return val.BoolValue(), true
+	}
+	if r.preset != nil {
+		if val, ok := r.preset.Options[name].(bool); ok {
+			return val, true
 		}
 	}
+	return r.h.getGlobalOverrideBoolValue(r.guildID, r.command, name)
+}
+
+// sources of flags, from the lowest precedence
+const (
+	flagSourceOverride = iota + 1
+	flagSourcePreset
+	flagSourceOption
+)
+
+func (r flagResolver) stringValue(name string) (string, bool) {
+	val, _, ok := r.stringSource(name)
+	return val, ok
+}
 
+// stringSource looks up the flag like stringValue, and reports the source of the value
+func (r flagResolver) stringSource(name string) (string, int, bool) {
+	if val, ok := optionValue(r.options, name); ok {
+		return val.StringValue(), flagSourceOption, true
+	}
+	if r.preset != nil {
+		if val, ok := r.preset.Options[name].(string); ok {
+			return val, flagSourcePreset, true
+		}
+	}
+	val, ok := r.h.getGlobalOverrideStringValue(r.guildID, r.command, name)
+	return val, flagSourceOverride, ok
+}
+
+func (r flagResolver) uintValue(name string) (uint64, bool) {
+	if val, ok := optionValue(r.options, name); ok {
+		return val.UintValue(), true
+	}
+	if r.preset != nil {
+		// JSON numbers are decoded as float64
+		if val, ok := r.preset.Options[name].(float64); ok && val >= 0 {
+			return uint64(val), true
+		}
+	}
+	val, ok := r.h.getGlobalOverrideStringValue(r.guildID, r.command, name)
+	if !ok {
+		return 0, false
+	}
+	parsed, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		log.Println(fmt.Sprintf("Unable to interpret global command overwrite as uint: %v", val))
+		return 0, false
+	}
+	return parsed, true
+}
+
+func (r flagResolver) customMods() string {
+	thunderstoreMods, _ := r.stringValue(CreateServerCustomThunderstoreMods)
 	return thunderstoreMods
 }
 
-func (h *handler) getGlobalOverrideBoolValue(command, flag string) (bool, bool) {
-	for _, commandOverride := range h.CommandOverrides {
-		if command == commandOverride.Command && flag == commandOverride.Flag {
-			value, err := strconv.ParseBool(commandOverride.Value)
-			if err != nil {
-				log.Println(fmt.Sprintf("Unable to interpret global command overwrite as bool: %v", commandOverride.Value))
-			}
-			return value, true
+func (h *handler) newFlagResolver(ctx context.Context, interaction *discordgo.InteractionCreate) (flagResolver, error) {
+	resolver := flagResolver{
+		h:       h,
+		guildID: interaction.GuildID,
+		command: interaction.ApplicationCommandData().Name,
+		options: interaction.ApplicationCommandData().Options,
+	}
+
+	if val, ok := optionValue(resolver.options, CreateServerPreset); ok {
+		p, err := h.presetRepo.GetByName(ctx, interaction.GuildID, interaction.Member.User.ID, val.StringValue())
+		if err != nil {
+			return flagResolver{}, fmt.Errorf("unable to find preset %s: %w", val.StringValue(), err)
 		}
+		resolver.preset = p
+	}
+
+	if val, ok := optionValue(resolver.options, PresetMods); ok {
+		mods, err := parseModList(val.StringValue())
+		if err != nil {
+			return flagResolver{}, fmt.Errorf("unable to parse mods: %w", err)
+		}
+		resolver.mods = mods
+	}
+
+	return resolver, nil
+}
+
+func (h *handler) getGlobalOverrideBoolValue(guildID, command, flag string) (bool, bool) {
+	commandOverride, ok := h.guilds[guildID].overrides.lookup(command, flag)
+	if !ok {
+		return false, false
 	}
-	return false, false
+	value, err := strconv.ParseBool(commandOverride)
+	if err != nil {
+		log.Println(fmt.Sprintf("Unable to interpret global command overwrite as bool: %v", commandOverride))
+	}
+	return value, true
 }
 
-func (h *handler) getGlobalOverrideStringValue(command, flag string) (string, bool) {
-	for _, commandOverride := range h.CommandOverrides {
-		if command == commandOverride.Command && flag == commandOverride.Flag {
-			return commandOverride.Value, true
+func (h *handler) getGlobalOverrideStringValue(guildID, command, flag string) (string, bool) {
+	return h.guilds[guildID].overrides.lookup(command, flag)
+}
+
+var ErrNoRegion = errors.New("region must be specified, either explicitly or through a preset")
+
+// enabledMods returns mods enabled on the server, including the implied ones
+func enabledMods(modOptions map[string]interface{}) (map[string]bool, error) {
+	var names []string
+	for name, value := range modOptions {
+		if enabled, ok := value.(bool); ok && enabled {
+			names = append(names, name)
 		}
 	}
-	return "", false
+	resolved, err := mod.ResolveMods(names)
+	if err != nil {
+		return nil, err
+	}
+	enabled := make(map[string]bool, len(resolved))
+	for _, name := range resolved {
+		enabled[name] = true
+	}
+	return enabled, nil
 }
 
-func (h *handler) defaultServer(name string, interaction *discordgo.InteractionCreate) (*nsserver.NSServer, error) {
+// modConVars parses mod_config, and validates it against the enabled mods
+func modConVars(flags flagResolver, enabled map[string]bool) (datatypes.JSONMap, error) {
+	option, ok := flags.stringValue(CreateServerModConfig)
+	if !ok || strings.TrimSpace(option) == "" {
+		return nil, nil
+	}
+	conVars, err := mod.ParseConVars(option)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mod.ValidateConVars(conVars, enabled)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(datatypes.JSONMap, len(conVars))
+	for key, value := range conVars {
+		result[key] = value
+	}
+	return result, nil
+}
+
+func (h *handler) defaultServer(name string, interaction *discordgo.InteractionCreate, flags flagResolver) (*nsserver.NSServer, error) {
 	var modOptions = make(map[string]interface{})
 	{
 		for modName := range mod.ByName {
 			modOptions[modName] = mod.ByName[modName]().EnabledByDefault()
-			val, ok := optionValue(interaction.ApplicationCommandData().Options, modName)
+			val, ok := flags.modValue(modName)
 			if ok {
-				modOptions[modName] = val.BoolValue()
-			} else {
-				val, ok := h.getGlobalOverrideBoolValue(interaction.ApplicationCommandData().Name, modName)
-				if ok {
-					modOptions[modName] = val
-				}
+				modOptions[modName] = val
 			}
 		}
-		thunderstoreMods := h.extractCustomMods(interaction)
+		thunderstoreMods := flags.customMods()
 
 		for _, m := range strings.Split(thunderstoreMods, ",") {
 			modName := strings.TrimSpace(m)
@@ -296,130 +748,125 @@ func (h *handler) defaultServer(name string, interaction *discordgo.InteractionC
 				modOptions[modName] = true
 			}
 		}
-	}
 
-	var tickRate uint64
-	{
-		val, ok := optionValue(interaction.ApplicationCommandData().Options, CreateServerTickRate)
-		if ok {
-			tickRate = val.UintValue()
+		if branch, ok := flags.stringValue(CreateServerCustomGithubBranch); ok && branch != "" {
+			if _, _, _, err := mod.ParseGithubBranch(branch); err != nil {
+				return nil, err
+			}
+			modOptions[mod.GithubBranchPrefix+strings.TrimPrefix(branch, mod.GithubBranchPrefix)] = true
 		}
 	}
 
-	var extraArgs string
-	{
-		val, ok := optionValue(interaction.ApplicationCommandData().Options, AdditionalExtraArgs)
-		if ok {
-			extraArgs = val.StringValue()
-		} else {
-			val, ok := h.getGlobalOverrideStringValue(interaction.ApplicationCommandData().Name, AdditionalExtraArgs)
-			if ok {
-				extraArgs = val
+	enabled, err := enabledMods(modOptions)
+	if err != nil {
+		return nil, err
+	}
+	conVars, err := modConVars(flags, enabled)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist, _ := flags.stringValue(CreateServerPlaylist)
+	mapName, _ := flags.stringValue(CreateServerMap)
+	mode, _ := flags.stringValue(CreateServerMode)
+	if err := mod.ValidateGameOptions(playlist, mapName, mode, enabled); err != nil {
+		return nil, err
+	}
+	limits := make(map[string]uint64)
+	for _, name := range []string{CreateServerMaxPlayers, CreateServerScoreLimit, CreateServerTimeLimit} {
+		if value, ok := flags.uintValue(name); ok {
+			if err := validateGameLimit(name, value); err != nil {
+				return nil, err
 			}
+			limits[name] = value
 		}
 	}
 
+	region, _ := flags.stringValue(CreateServerRegion)
+	if region == "" {
+		return nil, ErrNoRegion
+	}
+
+	tickRate, _ := flags.uintValue(CreateServerTickRate)
+	extraArgs, _ := flags.stringValue(AdditionalExtraArgs)
+
 	if tickRate != 0 && (tickRate < 20 || tickRate > 120) {
 		return nil, fmt.Errorf("tick_rate must be between 20, and 120. Following value is not supported: %d", tickRate)
 	}
 
-	var isInsecure bool
-	{
-		val, ok := optionValue(interaction.ApplicationCommandData().Options, CreateServerOptInsecure)
-		if ok {
-			isInsecure = val.BoolValue()
-		} else {
-			val, ok := h.getGlobalOverrideBoolValue(interaction.ApplicationCommandData().Name, CreateServerOptInsecure)
-			if ok {
-				isInsecure = val
-			} else {
-				isInsecure = false
-			}
-		}
-	}
+	isInsecure, _ := flags.boolValue(CreateServerOptInsecure)
+	isBareMetal, _ := flags.boolValue(CreateServerOptBareMetal)
 
-	var isBareMetal bool
-	{
-		val, ok := optionValue(interaction.ApplicationCommandData().Options, CreateServerOptBareMetal)
-		if ok {
-			isBareMetal = val.BoolValue()
-		} else {
-			val, ok := h.getGlobalOverrideBoolValue(interaction.ApplicationCommandData().Name, CreateServerOptBareMetal)
-			if ok {
-				isBareMetal = val
-			} else {
-				isBareMetal = false
-			}
-		}
+	masterServer, ok := flags.stringValue(CreateServerOptMasterServer)
+	if !ok {
+		masterServer = DefaultMasterServer
 	}
-
-	var masterServer string
-	{
-		val, ok := optionValue(interaction.ApplicationCommandData().Options, CreateServerOptMasterServer)
-		if ok {
-			masterServer = val.StringValue()
-		} else {
-			val, ok := h.getGlobalOverrideStringValue(interaction.ApplicationCommandData().Name, CreateServerOptMasterServer)
-			if ok {
-				masterServer = val
-			} else {
-				masterServer = DefaultMasterServer
-			}
-		}
+	if err := validateMasterServer(masterServer); err != nil {
+		return nil, err
 	}
 
 	var serverVersion string
 	var dockerImageVersion string
 	{
-		valServerVersion, okServerVersion := optionValue(interaction.ApplicationCommandData().Options, CreateServerVersionOpt)
-		valTagVersion, okTagVersion := optionValue(interaction.ApplicationCommandData().Options, CreateServerCustomDockerContainerOpt)
+		valServerVersion, serverVersionSource, okServerVersion := flags.stringSource(CreateServerVersionOpt)
+		valTagVersion, tagVersionSource, okTagVersion := flags.stringSource(CreateServerCustomDockerContainerOpt)
+
+		// the version, and the container are only conflicting, when they come from the same source. Otherwise the
+		// one with higher precedence wins, like an explicit option over the preset
+		if okServerVersion && okTagVersion {
+			switch {
+			case serverVersionSource == tagVersionSource:
+				return nil, fmt.Errorf("cannot specify both /%s and /%s", CreateServerVersionOpt, CreateServerCustomDockerContainerOpt)
+			case serverVersionSource > tagVersionSource:
+				okTagVersion = false
+			default:
+				okServerVersion = false
+			}
+		}
 
 		switch {
-		case okServerVersion && okTagVersion:
-			return nil, fmt.Errorf("cannot specify both /%s and /%s", CreateServerVersionOpt, CreateServerCustomDockerContainerOpt)
 		case okServerVersion:
-			dockerImageVersion = valServerVersion.StringValue()
-			for k, v := range util.NorthstarVersions {
+			dockerImageVersion = valServerVersion
+			for _, v := range util.NorthstarVersions() {
 				if v.DockerImage == dockerImageVersion {
-					serverVersion = k
+					serverVersion = v.Name
 					break
 				}
 			}
+			if serverVersion == "" {
+				return nil, fmt.Errorf("unknown server version: %s", dockerImageVersion)
+			}
 		case okTagVersion:
-			if util.DockerTagRegexp.MatchString(valTagVersion.StringValue()) {
-				dockerImageVersion = util.NorthstarDedicatedRepo + valTagVersion.StringValue()
+			if util.DockerTagRegexp.MatchString(valTagVersion) {
+				dockerImageVersion = util.NorthstarDedicatedRepo + valTagVersion
 				serverVersion = unknown
 			} else {
-				return nil, fmt.Errorf("invalid docker tag: %s. Must match following regex: %s", valTagVersion.StringValue(), util.DockerTagRegexp)
+				return nil, fmt.Errorf("invalid docker tag: %s. Must match following regex: %s", valTagVersion, util.DockerTagRegexp)
 			}
 		default:
 			serverVersion, dockerImageVersion = util.LatestStableDockerNorthstar()
 		}
 	}
 
-	pin := password.MustGenerate(PinLength, PinLength, 0, false, true)
-
-	var cheatsEnabled bool
-	{
-		val, ok := optionValue(interaction.ApplicationCommandData().Options, CreateServerOptCheatsEnabled)
-		if ok {
-			cheatsEnabled = val.BoolValue()
-		} else {
-			val, ok := h.getGlobalOverrideBoolValue(interaction.ApplicationCommandData().Name, CreateServerOptCheatsEnabled)
-			if ok {
-				cheatsEnabled = val
-			} else {
-				cheatsEnabled = false
-			}
-		}
+	identity, err := h.resolveServerIdentity(interaction, flags)
+	if err != nil {
+		return nil, err
 	}
+	pin := identity.password
+	if pin == "" {
+		pin = password.MustGenerate(PinLength, PinLength, 0, false, true)
+	}
+
+	cheatsEnabled, _ := flags.boolValue(CreateServerOptCheatsEnabled)
 
 	return &nsserver.NSServer{
-		Region:             interaction.ApplicationCommandData().Options[0].StringValue(),
+		Region:             region,
 		RequestedBy:        interaction.Member.User.ID,
+		GuildID:            interaction.GuildID,
 		Name:               name,
 		Pin:                pin,
 		ModOptions:         modOptions,
+		ModConVars:         conVars,
 		Insecure:           isInsecure,
 		BareMetal:          isBareMetal,
 		ServerVersion:      serverVersion,
@@ -430,16 +877,38 @@ func (h *handler) defaultServer(name string, interaction *discordgo.InteractionC
 		MasterServer:       masterServer,
 		EnableCheats:       cheatsEnabled,
 		ExtraArgs:          extraArgs,
+		Playlist:           playlist,
+		Map:                mapName,
+		Mode:               mode,
+		MaxPlayers:         limits[CreateServerMaxPlayers],
+		ScoreLimit:         limits[CreateServerScoreLimit],
+		TimeLimit:          limits[CreateServerTimeLimit],
+		DisplayName:        identity.displayName,
+		Description:        identity.description,
+		Public:             identity.public,
 	}, nil
 }
 
 const DefaultMasterServer = "https://northstar.tf"
 
+var ErrInvalidMasterServer = errors.New("master_server must be a http, or https url")
+
+// validateMasterServer accepts only urls of a host, as master server is passed to the server as is
+func validateMasterServer(masterServer string) error {
+	u, err := url.Parse(masterServer)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return fmt.Errorf("%w. Following value is not supported: %s", ErrInvalidMasterServer, masterServer)
+	}
+	return nil
+}
+
 func (h *handler) handleCreateServer(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
 	ctx := context.Background()
 
 	sendInteractionDeferred(session, interaction)
 
+	g := h.guildOf(interaction)
+
 	h.createLock.Lock()
 	defer h.createLock.Unlock()
 	if h.maxServerCreateRate != 0 && h.rateCounter.Rate() > int64(h.maxServerCreateRate) {
@@ -447,6 +916,11 @@ func (h *handler) handleCreateServer(session *discordgo.Session, interaction *di
 
 		return
 	}
+	if g.config.MaxServersPerHour != 0 && g.rateCounter.Rate() >= int64(g.config.MaxServersPerHour) {
+		editDeferredInteractionReply(session, interaction.Interaction, "This discord server has exceeded the maximum number of servers it can create per hour. Please try again later.", nil)
+
+		return
+	}
 	servers, err := h.p.GetRunningServers(ctx)
 	if err != nil {
 		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to list running servers: %v", err), nil)
@@ -464,6 +938,14 @@ func (h *handler) handleCreateServer(session *discordgo.Session, interaction *di
 
 		return
 	}
+	if g.config.MaxConcurrentInstances != 0 {
+		guildServers := h.guildServers(interaction.GuildID, servers, cachedServers)
+		if len(guildServers) >= int(g.config.MaxConcurrentInstances) {
+			editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("This discord server can't have more than %d servers", g.config.MaxConcurrentInstances), nil)
+
+			return
+		}
+	}
 
 	name, err := generateUniqueName(servers, cachedServers)
 	if err != nil {
@@ -472,7 +954,14 @@ func (h *handler) handleCreateServer(session *discordgo.Session, interaction *di
 		return
 	}
 
-	server, err := h.defaultServer(name, interaction)
+	flags, err := h.newFlagResolver(ctx, interaction)
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to create server: %v", err), nil)
+
+		return
+	}
+
+	server, err := h.defaultServer(name, interaction, flags)
 	if err != nil {
 		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to create server: %v", err), nil)
 
@@ -489,6 +978,9 @@ func (h *handler) handleCreateServer(session *discordgo.Session, interaction *di
 	if h.maxServerCreateRate != 0 {
 		h.rateCounter.Incr(1)
 	}
+	if g.rateCounter != nil {
+		g.rateCounter.Incr(1)
+	}
 
 	err = h.nsRepo.Store(ctx, []*nsserver.NSServer{server})
 	if err != nil {
@@ -498,73 +990,48 @@ func (h *handler) handleCreateServer(session *discordgo.Session, interaction *di
 	}
 
 	note := strings.Builder{}
-	note.WriteString(fmt.Sprintf("Created server **%s** in **%s**, with password: **%s**.", server.Name, server.Region, server.Pin))
-	note.WriteString("\n")
-
-	if server.Insecure {
-		note.WriteString(fmt.Sprintf("Insecure mode is enabled. If master server is offline, use: `connect %s:%d`", server.MainIP, server.GameUDPPort))
-		note.WriteString("\n")
-	}
 	timeToSpinUp := 5 // Now that we have to deal with broken vultr servers
 	if server.BareMetal {
 		timeToSpinUp = 20
-		note.WriteString(fmt.Sprintf("**This is a bare metal server. It will take longer to spin up, but will be more performant. Ideally, you should only use this if you are hosting a tournament.**"))
+		note.WriteString("**This is a bare metal server. It will take longer to spin up, but will be more performant. Ideally, you should only use this if you are hosting a tournament.**")
 		note.WriteString("\n")
 	}
-
-	note.WriteString(fmt.Sprintf("Server version: **%s**", server.ServerVersion))
-	note.WriteString(fmt.Sprintf(". Server will be up in: **%d** minutes(This could be affected by slowness in vultr regions, or github API)", timeToSpinUp))
-	if h.autoDeleteDuration != time.Duration(0) {
-		note.WriteString(fmt.Sprintf(", and autodeleted at <t:%d:R>", time.Now().Add(h.autoDeleteDuration).UnixNano()/1e9))
-	}
+	note.WriteString(fmt.Sprintf("Server will be up in: **%d** minutes(This could be affected by slowness in vultr regions, or github API)", timeToSpinUp))
 	note.WriteString("\n")
 
-	modInfo := ""
-	modInfoMisc := ""
-
-	for modName := range server.ModOptions {
-		_, knownMod := mod.ByName[modName]
-		thunderstoreMods := h.extractCustomMods(interaction)
-		if knownMod || strings.Contains(thunderstoreMods, modName) {
-			enabled, ok := server.ModOptions[modName].(bool)
-			if ok && enabled {
-				if modInfo == "" {
-					modInfo = "Following Mods Are Enabled:\n"
-				}
-				modInfo += fmt.Sprintf(" - %s(version: %s)\n", modName, server.ModOptions[modName+util.VersionPostfix])
-
-				if server.ModOptions[modName+util.RequiredByClientPostfix] == true {
-					if modInfoMisc == "" {
-						modInfoMisc = "Following mods are **REQUIRED TO BE DOWNLOADED BY CLIENT**:\n"
-					}
-					modInfoMisc += fmt.Sprintf(" - %s: <%s>\n", modName, server.ModOptions[modName+util.LinkPostfix])
-				}
-			}
-		}
-	}
-
-	if modInfo != "" {
+	if server.Insecure {
+		note.WriteString("Insecure mode is enabled. If master server is offline, use the connect command below")
 		note.WriteString("\n")
-		note.WriteString(modInfo)
-		if modInfoMisc != "" {
-			note.WriteString(modInfoMisc)
-		}
 	}
 
 	if server.EnableCheats {
-		note.WriteString("\n")
 		note.WriteString("**Cheats are enabled.**")
 		note.WriteString("\n")
 	}
 
 	if server.TickRate != 0 {
+		note.WriteString("Custom tick_rate value supplied. **Make sure clients have the following console variables:**")
 		note.WriteString("\n")
-		note.WriteString("Custom tick_rate value supplied.\n**Make sure clients have the following console variables:**")
+		note.WriteString(fmt.Sprintf("`cl_updaterate_mp %d`", server.TickRate))
+	}
+
+	h.statusBoard.requestRefresh()
+
+	var files []*discordgo.File
+	profile, err := clientProfile(server)
+	switch {
+	case err == nil:
+		note.WriteString("Clients can import the attached r2modman profile, to install the required mods")
 		note.WriteString("\n")
-		note.WriteString(fmt.Sprintf("cl_updaterate_mp %d", server.TickRate))
+		files = append(files, profile)
+	case !errors.Is(err, ErrNoClientMods):
+		log.Println(fmt.Sprintf("unable to export client profile of %s: %v", server.Name, err))
 	}
 
-	editDeferredInteractionReply(session, interaction.Interaction, note.String(), nil)
+	embed := h.serverEmbed(server)
+	embed.Title = fmt.Sprintf("Created server %s", server.Name)
+	embed.Description = note.String()
+	editDeferredInteractionEmbeds(session, interaction.Interaction, "", []*discordgo.MessageEmbed{embed}, serverControls(server), files)
 }
 
 var ErrUnableToGenerateUniqueName = errors.New("unable to generate unique name")
@@ -597,54 +1064,38 @@ func generateUniqueName(servers []*nsserver.NSServer, cachedServers []*nsserver.
 	return name, nil
 }
 
-func (h *handler) handleCommandFlagOverrides(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
-	sendInteractionDeferred(session, interaction)
-	b, err := json.Marshal(h.CommandOverrides)
-	if err != nil {
-		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to marshal command flag overrides: %v", err), nil)
-		return
-	}
-	editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("```%s```", string(b)), nil)
-}
-
 func (h *handler) handleDeleteServer(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
 	ctx := context.Background()
 	serverName := interaction.ApplicationCommandData().Options[0].StringValue()
 	sendInteractionDeferred(session, interaction)
 
-	server, err := h.nsRepo.GetByName(ctx, serverName)
-	if err != nil {
+	server, err := h.guildServer(ctx, interaction, serverName)
+	switch {
+	case errors.Is(err, ErrServerNotOwned):
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("failed to delete the target server. error: %v", err), nil)
+
+		return
+	case err != nil:
 		log.Println(fmt.Sprintf("unable to get server by name: %v", err))
 
-		server = &nsserver.NSServer{
-			Name: serverName,
+		if interaction.GuildID != h.defaultGuildID {
+			editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("failed to get server from cache database. error: %v", err), nil)
+
+			return
 		}
-	}
 
-	if h.notifier != nil {
-		logs, err := h.p.ExtractServerLogs(ctx, server)
-		if err != nil {
-			log.Println(fmt.Sprintf("unable to extract logs for server: %v", err))
-			h.notifier.NotifyServer(server, fmt.Sprintf("unable to extract logs for server: %v", err))
-		} else {
-			go h.notifier.NotifyAndAttachServerData(server, "Deleted, logs:", fmt.Sprintf("%s.log.zip", server.Name), logs)
+		server = &nsserver.NSServer{
+			Name: serverName,
 		}
 	}
 
-	err = h.p.DeleteServer(ctx, &nsserver.NSServer{
-		Name: serverName,
-	})
+	err = h.deleteServer(ctx, server)
 	if err != nil {
-		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("failed to delete the target server. error: %v", err), nil)
+		editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
 
 		return
 	}
 
-	err = h.nsRepo.DeleteByName(ctx, serverName)
-	if err != nil {
-		log.Println(fmt.Sprintf("unable to delete server from the database: %v", err))
-	}
-
 	editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("deleted server %s", serverName), nil)
 }
 
@@ -654,7 +1105,7 @@ func (h *handler) handleRestartServer(session *discordgo.Session, interaction *d
 
 	sendInteractionDeferred(session, interaction)
 
-	server, err := h.nsRepo.GetByName(ctx, serverName)
+	server, err := h.guildServer(ctx, interaction, serverName)
 	if err != nil {
 		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("failed to get server from cache database. error: %v", err), nil)
 
@@ -682,33 +1133,16 @@ func (h *handler) handleServerExtendLifetime(session *discordgo.Session, interac
 		return
 	}
 
-	if extend <= 0 {
-		editDeferredInteractionReply(session, interaction.Interaction, "duration should not be negative, or 0", nil)
-
-		return
-	}
-
-	server, err := h.nsRepo.GetByName(ctx, serverName)
+	server, err := h.guildServer(ctx, interaction, serverName)
 	if err != nil {
 		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("failed to get server from cache database. error: %v", err), nil)
 
 		return
 	}
 
-	if server.ExtendLifetime != nil {
-		extend += *server.ExtendLifetime
-	}
-
-	if extend > h.maxExtendDuration {
-		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("extended lifetime exceeded maximum allowed extended duration. Extended duration: %s, Max extended duration: %s", extend.String(), h.maxExtendDuration.String()), nil)
-
-		return
-	}
-
-	server.ExtendLifetime = &extend
-	err = h.nsRepo.Update(ctx, server)
+	err = h.extendServer(ctx, interaction, server, extend)
 	if err != nil {
-		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("Failed to update ExtendLifetime field in database, error: %v", err), nil)
+		editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
 
 		return
 	}
@@ -722,7 +1156,7 @@ func (h *handler) handleServerMetadata(session *discordgo.Session, interaction *
 
 	sendInteractionDeferred(session, interaction)
 
-	server, err := h.nsRepo.GetByName(ctx, serverName)
+	server, err := h.guildServer(ctx, interaction, serverName)
 	if err != nil {
 		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("failed to get server from cache database. error: %v", err), nil)
 
@@ -772,7 +1206,7 @@ func (h *handler) handleListServer(session *discordgo.Session, interaction *disc
 		if ok {
 			verbose = val.BoolValue()
 		} else {
-			val, ok := h.getGlobalOverrideBoolValue(interaction.ApplicationCommandData().Name, ListServerVerbosityOpt)
+			val, ok := h.getGlobalOverrideBoolValue(interaction.GuildID, interaction.ApplicationCommandData().Name, ListServerVerbosityOpt)
 			if ok {
 				verbose = val
 			} else {
@@ -781,49 +1215,41 @@ func (h *handler) handleListServer(session *discordgo.Session, interaction *disc
 		}
 	}
 
-	for _, cached := range cachedServers {
-		for _, server := range nsservers {
-			if server.Name == cached.Name {
-				server.Pin = cached.Pin
-				server.RequestedBy = cached.RequestedBy
-				server.ModOptions = cached.ModOptions
-				server.ExtendLifetime = cached.ExtendLifetime
-				break
-			}
-		}
-	}
-
-	servers := make([]string, len(nsservers))
+	mergeCachedServers(nsservers, cachedServers)
+	nsservers = h.guildServers(interaction.GuildID, nsservers, cachedServers)
 
 	if len(nsservers) == 0 {
 		editDeferredInteractionReply(session, interaction.Interaction, "No servers running", nil)
 
 		return
 	}
-	for idx, server := range nsservers {
-		pin := unknown
-		if server.Pin != "" {
-			pin = server.Pin
+
+	if len(nsservers) <= maxEmbedsPerMessage {
+		embeds := make([]*discordgo.MessageEmbed, len(nsservers))
+		for idx, server := range nsservers {
+			embeds[idx] = h.serverEmbed(server)
+			if verbose {
+				embeds[idx].Fields = append(embeds[idx].Fields, &discordgo.MessageEmbedField{Name: "Options", Value: truncateField(fmt.Sprintf("```\n%s```", serverOptions(server)))})
+			}
 		}
+		editDeferredInteractionEmbeds(session, interaction.Interaction, "", embeds, serverSelect(nsservers), nil)
+
+		return
+	}
+
+	servers := make([]string, len(nsservers))
+	for idx, server := range nsservers {
 		user := unknown
 		if server.RequestedBy != "" {
 			user = server.RequestedBy
 		}
-		options := ""
-		if server.ModOptions != nil {
-			j, err := server.ModOptions.MarshalJSON()
-			if err != nil {
-				options = fmt.Sprintf("failed to parse servers options. error: %v", err)
-			} else {
-				options = string(j)
-			}
-		}
+		options := serverOptions(server)
 		builder := strings.Builder{}
 		builder.WriteString(fmt.Sprintf("Name: %s", server.Name))
 		builder.WriteString("\n")
 		builder.WriteString(fmt.Sprintf("Region: %s", server.Region))
 		builder.WriteString("\n")
-		builder.WriteString(fmt.Sprintf("Pin: `%s`", pin))
+		builder.WriteString(fmt.Sprintf("Password: %s", serverPassword(server)))
 		builder.WriteString("\n")
 		builder.WriteString(fmt.Sprintf("Server Version: %s", server.ServerVersion))
 		builder.WriteString("\n")
@@ -844,56 +1270,66 @@ func (h *handler) handleListServer(session *discordgo.Session, interaction *disc
 		if options != "" && verbose {
 			builder.WriteString(fmt.Sprintf("Options: \n```\n%s```\n", options))
 		}
-		if h.autoDeleteDuration > time.Duration(0) {
-			autoDelete := h.autoDeleteDuration
-			if server.ExtendLifetime != nil {
-				autoDelete += *server.ExtendLifetime
-			}
-			builder.WriteString(fmt.Sprintf("Time until deleted: %s", (autoDelete - time.Since(server.CreatedAt)).String()))
+		if deleteAt, ok := h.deleteAt(server); ok {
+			builder.WriteString(fmt.Sprintf("Time until deleted: %s", time.Until(deleteAt).String()))
 		}
 		builder.WriteString("\n\n")
 		servers[idx] = builder.String()
 	}
 
-	message := strings.Join(servers, "\n")
-	var files []*discordgo.File
-	if len(message) > 1900 {
-		files = []*discordgo.File{{
-			Name:        "list_server.txt",
-			ContentType: "application/octet-stream",
-			Reader:      strings.NewReader(message),
-		}}
+	files := []*discordgo.File{{
+		Name:        "list_server.txt",
+		ContentType: "application/octet-stream",
+		Reader:      strings.NewReader(strings.Join(servers, "\n")),
+	}}
+
+	editDeferredInteractionReply(session, interaction.Interaction, "List of servers is too long to be sent in a message. Sending as a file instead.", files)
+}
 
-		message = "List of servers is too long to be sent in a message. Sending as a file instead."
+// mergeCachedServers fills running servers with the details only known to the database. Region, and bare metal
+// are known to the provider, and are kept
+func mergeCachedServers(servers []*nsserver.NSServer, cachedServers []*nsserver.NSServer) {
+	for _, cached := range cachedServers {
+		for _, server := range servers {
+			if server.Name == cached.Name {
+				region, bareMetal := server.Region, server.BareMetal
+				*server = *cached
+				server.Region = region
+				server.BareMetal = server.BareMetal || bareMetal
+				break
+			}
+		}
 	}
+}
 
-	editDeferredInteractionReply(session, interaction.Interaction, message, files)
+// serverOptions formats mod options of the server as json
+func serverOptions(server *nsserver.NSServer) string {
+	if server.ModOptions == nil {
+		return ""
+	}
+	j, err := server.ModOptions.MarshalJSON()
+	if err != nil {
+		return fmt.Sprintf("failed to parse servers options. error: %v", err)
+	}
+	return string(j)
 }
 
 func (h *handler) handleExtractLogs(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
 	ctx := context.Background()
 	serverName := interaction.ApplicationCommandData().Options[0].StringValue()
 	sendInteractionDeferred(session, interaction)
-	server, err := h.nsRepo.GetByName(ctx, serverName)
+	server, err := h.guildServer(ctx, interaction, serverName)
 	if err != nil {
 		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("failed to get server from cache database. error: %v", err), nil)
 
 		return
 	}
 
-	file, err := h.p.ExtractServerLogs(ctx, server)
+	err = h.sendLogs(ctx, session, interaction, server)
 	if err != nil {
-		failure := fmt.Sprintf("failed to extract logs from target server. error: %v", err)
-		editDeferredInteractionReply(session, interaction.Interaction, failure, nil)
+		editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
 		return
 	}
-
-	files := []*discordgo.File{{
-		Name:        fmt.Sprintf("%s.log.zip", server.Name),
-		ContentType: "application/octet-stream",
-		Reader:      file,
-	}}
-
-	go sendMessageWithFilesDM(session, interaction.Member.User.ID, fmt.Sprintf("logs extracted from server %s", serverName), files)
 	editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("logs extraction for server %s is completed, and are sent privately to you", serverName), nil)
 }
@@ -12,17 +12,24 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+	"github.com/l1ghthouse/northstar-bootstrap/src/override"
+	"github.com/l1ghthouse/northstar-bootstrap/src/preset"
 	"github.com/l1ghthouse/northstar-bootstrap/src/providers"
+	"github.com/l1ghthouse/northstar-bootstrap/src/providers/util"
 	"github.com/paulbellamy/ratecounter"
 )
 
 type Config struct {
-	DcBotToken       string `required:"true"`
-	DcGuildID        string `required:"true"`
+	DcBotToken string `required:"true"`
+	// DcGuildID, BotReportChannel, StatusChannel and CommandDefaults configure the default discord server.
+	// Additional discord servers are configured with Guilds
+	DcGuildID        string ``
 	BotReportChannel string ``
+	StatusChannel    string ``
 	// very hardcody, really needs a concept along the lines of: "Data PostProcessing"
 	RebalancedLTSRankingMongoDBString string ``
 	CommandDefaults                   []CommandOverrides
+	Guilds                            []GuildConfig
 }
 
 type CommandOverrides struct {
@@ -31,6 +38,9 @@ type CommandOverrides struct {
 	Value   string `required:"true"`
 }
 
+// startupVersionsTimeout bounds how long the startup waits for the northstar versions
+const startupVersionsTimeout = 20 * time.Second
+
 type discordBot struct {
 	config        Config
 	ctx           context.Context
@@ -38,7 +48,7 @@ type discordBot struct {
 	closeChannels []chan struct{}
 }
 
-func (d *discordBot) Start(provider providers.Provider, nsRepo nsserver.Repo, maxConcurrentServers, maxServersPerHour uint, autoDeleteDuration time.Duration, maxExtendDuration time.Duration) (*autodelete.Manager, error) {
+func (d *discordBot) Start(provider providers.Provider, nsRepo nsserver.Repo, presetRepo preset.Repo, overrideRepo override.Repo, maxConcurrentServers, maxServersPerHour uint, autoDeleteDuration time.Duration, maxExtendDuration time.Duration) (*autodelete.Manager, error) {
 	discordClient, err := discordgo.New("Bot " + d.config.DcBotToken)
 	if err != nil {
 		log.Fatal("Error creating Discord session: ", err)
@@ -48,20 +58,50 @@ func (d *discordBot) Start(provider providers.Provider, nsRepo nsserver.Repo, ma
 		counter = ratecounter.NewRateCounter(time.Hour)
 	}
 
-	notifier := NewNotifier(discordClient, d.config.BotReportChannel, d.config.RebalancedLTSRankingMongoDBString)
+	guildConfigs, err := d.config.guildConfigs()
+	if err != nil {
+		return nil, err
+	}
+	defaultGuildID := guildConfigs[0].GuildID
+
+	// server_version overrides are validated against the versions offered, so the catalogue is refreshed first
+	versionsCtx, cancel := context.WithTimeout(d.ctx, startupVersionsTimeout)
+	if _, err := util.RefreshNorthstarVersions(versionsCtx); err != nil {
+		log.Printf("unable to refresh northstar versions, fallback versions are offered until the next refresh: %v", err)
+	}
+	cancel()
+
+	guilds := make(map[string]*guild, len(guildConfigs))
+	reportChannels := make(map[string]string, len(guildConfigs))
+	statusChannels := make(map[string]string, len(guildConfigs))
+	for _, guildConfig := range guildConfigs {
+		g, err := newGuild(d.ctx, guildConfig, guildConfig.GuildID == defaultGuildID, overrideRepo, maxExtendDuration)
+		if err != nil {
+			return nil, err
+		}
+		guilds[guildConfig.GuildID] = g
+		reportChannels[guildConfig.GuildID] = guildConfig.BotReportChannel
+		statusChannels[guildConfig.GuildID] = guildConfig.StatusChannel
+	}
+
+	notifier := NewNotifier(discordClient, reportChannels, defaultGuildID, d.config.RebalancedLTSRankingMongoDBString)
 
-	botHandler := handler{
+	botHandler := &handler{
 		p:                    provider,
 		maxConcurrentServers: maxConcurrentServers,
 		autoDeleteDuration:   autoDeleteDuration,
 		nsRepo:               nsRepo,
 		maxServerCreateRate:  maxServersPerHour,
-		maxExtendDuration:    maxExtendDuration,
 		rateCounter:          counter,
 		createLock:           &sync.Mutex{},
-		CommandOverrides:     d.config.CommandDefaults,
 		notifier:             notifier,
+		presetRepo:           presetRepo,
+		guilds:               guilds,
+		defaultGuildID:       defaultGuildID,
+		autocompleter:        newAutocompleter(provider),
 	}
+	botHandler.statusBoard = newStatusBoard(discordClient, botHandler, statusChannels)
+	botHandler.autocompleter.warm()
 
 	commandHandlers := map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
 	commandHandlers[CreateServer] = botHandler.handleCreateServer
@@ -72,10 +112,36 @@ func (d *discordBot) Start(provider providers.Provider, nsRepo nsserver.Repo, ma
 	commandHandlers[ServerMetadata] = botHandler.handleServerMetadata
 	commandHandlers[ExtendLifetime] = botHandler.handleServerExtendLifetime
 	commandHandlers[CommandFlagOverrides] = botHandler.handleCommandFlagOverrides
+	commandHandlers[Preset] = botHandler.handlePreset
+	commandHandlers[Overrides] = botHandler.handleOverrides
+	commandHandlers[SearchMods] = botHandler.handleSearchMods
+	commandHandlers[ClientProfile] = botHandler.handleClientProfile
+	commandHandlers[ModInfo] = botHandler.handleModInfo
+	commandHandlers[BuildImage] = botHandler.handleBuildImage
 
 	discordClient.AddHandler(func(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
-		if handlerFunc, ok := commandHandlers[interaction.ApplicationCommandData().Name]; ok {
-			handlerFunc(session, interaction)
+		if !botHandler.authorize(session, interaction) {
+			return
+		}
+		switch interaction.Type {
+		case discordgo.InteractionApplicationCommand:
+			if handlerFunc, ok := commandHandlers[interaction.ApplicationCommandData().Name]; ok {
+				handlerFunc(session, interaction)
+			}
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			botHandler.handleAutocomplete(session, interaction)
+		case discordgo.InteractionMessageComponent:
+			if isModComponentID(interaction.MessageComponentData().CustomID) {
+				botHandler.handleModComponent(session, interaction)
+			} else {
+				botHandler.handleServerComponent(session, interaction)
+			}
+		case discordgo.InteractionModalSubmit:
+			if isModComponentID(interaction.ModalSubmitData().CustomID) {
+				botHandler.handleModModal(session, interaction)
+			} else {
+				botHandler.handleServerModal(session, interaction)
+			}
 		}
 	})
 
@@ -91,21 +157,48 @@ func (d *discordBot) Start(provider providers.Provider, nsRepo nsserver.Repo, ma
 		return nil, fmt.Errorf("error opening Discord connection: %w", err)
 	}
 
-	cmd, err := discordClient.ApplicationCommands(discordClient.State.User.ID, d.config.DcGuildID)
+	for guildID := range guilds {
+		err = registerCommands(discordClient, guildID, commandHandlers)
+		if err != nil {
+			return nil, fmt.Errorf("discord server %s: %w", guildID, err)
+		}
+	}
+
+	// server_version choices are registered with the commands
+	go util.WatchNorthstarVersions(d.ctx, func() {
+		for guildID := range guilds {
+			if err := registerCommands(discordClient, guildID, commandHandlers); err != nil {
+				log.Printf("unable to register commands with new northstar versions in discord server %s: %v", guildID, err)
+			}
+		}
+	})
+
+	go botHandler.statusBoard.run(d.ctx)
+	botHandler.statusBoard.requestRefresh()
+
+	autoDeleteManager := autodelete.NewAutoDeleteManager(nsRepo, provider, notifier, autoDeleteDuration)
+	autoDeleteManager.OnTick(func(ctx context.Context) {
+		botHandler.statusBoard.requestRefresh()
+	})
+	return autoDeleteManager, nil
+}
+
+func registerCommands(discordClient *discordgo.Session, guildID string, commandHandlers map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate)) error {
+	cmd, err := discordClient.ApplicationCommands(discordClient.State.User.ID, guildID)
 	if err != nil {
-		return nil, fmt.Errorf("error getting commands: %w", err)
+		return fmt.Errorf("error getting commands: %w", err)
 	}
 
 	for _, c := range cmd {
 		if _, ok := commandHandlers[c.Name]; !ok {
-			err = discordClient.ApplicationCommandDelete(discordClient.State.User.ID, d.config.DcGuildID, c.ID)
+			err = discordClient.ApplicationCommandDelete(discordClient.State.User.ID, guildID, c.ID)
 			if err != nil {
-				return nil, fmt.Errorf("error deleting command: %w", err)
+				return fmt.Errorf("error deleting command: %w", err)
 			}
 		}
 	}
 
-	for _, newCommand := range commands {
+	for _, newCommand := range applicationCommands() {
 		var oldCommand *discordgo.ApplicationCommand
 		for _, c := range cmd {
 			if c.Name == newCommand.Name {
@@ -114,19 +207,18 @@ func (d *discordBot) Start(provider providers.Provider, nsRepo nsserver.Repo, ma
 		}
 
 		if oldCommand == nil {
-			_, err = discordClient.ApplicationCommandCreate(discordClient.State.User.ID, d.config.DcGuildID, newCommand)
+			_, err = discordClient.ApplicationCommandCreate(discordClient.State.User.ID, guildID, newCommand)
 			if err != nil {
-				return nil, fmt.Errorf("cannot create '%v' command: %w", newCommand.Name, err)
+				return fmt.Errorf("cannot create '%v' command: %w", newCommand.Name, err)
 			}
 		} else {
-			_, err = discordClient.ApplicationCommandEdit(discordClient.State.User.ID, d.config.DcGuildID, oldCommand.ID, newCommand)
+			_, err = discordClient.ApplicationCommandEdit(discordClient.State.User.ID, guildID, oldCommand.ID, newCommand)
 			if err != nil {
-				return nil, fmt.Errorf("cannot update '%v' command: %w", newCommand.Name, err)
+				return fmt.Errorf("cannot update '%v' command: %w", newCommand.Name, err)
 			}
 		}
 	}
-
-	return autodelete.NewAutoDeleteManager(nsRepo, provider, notifier, autoDeleteDuration), nil
+	return nil
 }
 
 func (d *discordBot) gracefulDiscordClose(discordClient io.Closer, callbackDone chan struct{}) {
@@ -0,0 +1,205 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+	"github.com/l1ghthouse/northstar-bootstrap/src/override"
+	"github.com/paulbellamy/ratecounter"
+)
+
+// GuildConfig configures a single discord server the bot serves. Limits set to 0 fall back to the global limits
+type GuildConfig struct {
+	GuildID          string `required:"true"`
+	BotReportChannel string ``
+	// StatusChannel gets a pinned message listing running servers, that is kept up to date
+	StatusChannel                  string ``
+	CommandDefaults                []CommandOverrides
+	MaxConcurrentInstances         uint `default:"0"`
+	MaxServersPerHour              uint `default:"0"`
+	MaxServerExtendDurationSeconds uint `default:"0"`
+	// AdminRoles are allowed to change command overrides, and discord server wide presets, in addition to
+	// members with the Manage Server permission
+	AdminRoles []string
+	// AllowedRoles restricts who can use the bot. Empty list allows everyone
+	AllowedRoles []string
+	// PublicServerRoles are allowed to create servers without a password, in addition to admins
+	PublicServerRoles []string
+}
+
+var ErrNoGuilds = errors.New("no discord servers configured")
+
+// guildConfigs returns configured guilds. Top level DcGuildID is kept for backwards compatibility, and is the
+// default guild when set.
+func (c Config) guildConfigs() ([]GuildConfig, error) {
+	guilds := make([]GuildConfig, 0, len(c.Guilds)+1)
+	if c.DcGuildID != "" {
+		guilds = append(guilds, GuildConfig{
+			GuildID:          c.DcGuildID,
+			BotReportChannel: c.BotReportChannel,
+			StatusChannel:    c.StatusChannel,
+			CommandDefaults:  c.CommandDefaults,
+		})
+	}
+
+	guilds = append(guilds, c.Guilds...)
+	if len(guilds) == 0 {
+		return nil, ErrNoGuilds
+	}
+
+	seen := make(map[string]bool)
+	for _, g := range guilds {
+		if seen[g.GuildID] {
+			return nil, fmt.Errorf("discord server %s is configured more than once", g.GuildID)
+		}
+		seen[g.GuildID] = true
+	}
+	return guilds, nil
+}
+
+type guild struct {
+	config            GuildConfig
+	overrides         *commandOverrides
+	rateCounter       *ratecounter.RateCounter
+	maxExtendDuration time.Duration
+}
+
+func newGuild(ctx context.Context, config GuildConfig, isDefault bool, overrideRepo override.Repo, maxExtendDuration time.Duration) (*guild, error) {
+	overrides, err := newCommandOverrides(ctx, config.GuildID, isDefault, config.CommandDefaults, overrideRepo)
+	if err != nil {
+		return nil, fmt.Errorf("discord server %s: %w", config.GuildID, err)
+	}
+
+	g := &guild{
+		config:            config,
+		overrides:         overrides,
+		maxExtendDuration: maxExtendDuration,
+	}
+	if config.MaxServersPerHour != 0 {
+		g.rateCounter = ratecounter.NewRateCounter(time.Hour)
+	}
+	if config.MaxServerExtendDurationSeconds != 0 {
+		g.maxExtendDuration = time.Duration(config.MaxServerExtendDurationSeconds) * time.Second
+	}
+	return g, nil
+}
+
+func (g *guild) hasAnyRole(member *discordgo.Member, roles []string) bool {
+	for _, role := range roles {
+		for _, memberRole := range member.Roles {
+			if role == memberRole {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (g *guild) isAllowed(member *discordgo.Member) bool {
+	return len(g.config.AllowedRoles) == 0 || g.hasAnyRole(member, g.config.AllowedRoles)
+}
+
+func (g *guild) isAdmin(member *discordgo.Member) bool {
+	if member == nil {
+		return false
+	}
+	return member.Permissions&discordgo.PermissionManageServer == discordgo.PermissionManageServer ||
+		member.Permissions&discordgo.PermissionAdministrator == discordgo.PermissionAdministrator ||
+		g.hasAnyRole(member, g.config.AdminRoles)
+}
+
+func (g *guild) canCreatePublic(member *discordgo.Member) bool {
+	return g.isAdmin(member) || g.hasAnyRole(member, g.config.PublicServerRoles)
+}
+
+func (h *handler) guildOf(interaction *discordgo.InteractionCreate) *guild {
+	return h.guilds[interaction.GuildID]
+}
+
+func (h *handler) isAdmin(interaction *discordgo.InteractionCreate) bool {
+	g := h.guildOf(interaction)
+	return g != nil && g.isAdmin(interaction.Member)
+}
+
+// isBotAdmin checks if the member is an admin of the default guild, whose admins manage resources shared by every
+// guild
+func (h *handler) isBotAdmin(interaction *discordgo.InteractionCreate) bool {
+	return interaction.GuildID == h.defaultGuildID && h.isAdmin(interaction)
+}
+
+// ownsServer checks if the server was created from the guild. Servers created before multi guild support,
+// or outside the bot belong to the default guild.
+func (h *handler) ownsServer(guildID string, server *nsserver.NSServer) bool {
+	if server.GuildID == "" {
+		return guildID == h.defaultGuildID
+	}
+	return server.GuildID == guildID
+}
+
+var ErrServerNotOwned = errors.New("server belongs to another discord server")
+
+// guildServer returns server from the database, if it belongs to the guild of the interaction
+func (h *handler) guildServer(ctx context.Context, interaction *discordgo.InteractionCreate, name string) (*nsserver.NSServer, error) {
+	server, err := h.nsRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !h.ownsServer(interaction.GuildID, server) {
+		return nil, fmt.Errorf("%w: %s", ErrServerNotOwned, name)
+	}
+	return server, nil
+}
+
+// authorize responds to the interaction, if it comes from an unknown discord server, or from a member who is
+// not allowed to use the bot
+func (h *handler) authorize(session *discordgo.Session, interaction *discordgo.InteractionCreate) bool {
+	var reason string
+	g := h.guildOf(interaction)
+	switch {
+	case g == nil || interaction.Member == nil:
+		reason = "This bot is not configured for this discord server"
+	case !g.isAllowed(interaction.Member):
+		reason = "You are not allowed to use this bot"
+	default:
+		return true
+	}
+
+	// autocomplete interactions can only be responded to with choices
+	if interaction.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		return false
+	}
+
+	if err := session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: reason,
+			Flags:   uint64(discordgo.MessageFlagsEphemeral),
+		},
+	}); err != nil {
+		log.Println("Error sending message: ", err)
+	}
+	return false
+}
+
+// guildServers returns running servers that belong to the guild. Owning guild of the running server is taken from
+// the cached server with the same name.
+func (h *handler) guildServers(guildID string, servers []*nsserver.NSServer, cachedServers []*nsserver.NSServer) []*nsserver.NSServer {
+	owned := make([]*nsserver.NSServer, 0, len(servers))
+	for _, server := range servers {
+		for _, cached := range cachedServers {
+			if server.Name == cached.Name {
+				server.GuildID = cached.GuildID
+				break
+			}
+		}
+		if h.ownsServer(guildID, server) {
+			owned = append(owned, server)
+		}
+	}
+	return owned
+}
@@ -0,0 +1,96 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+)
+
+const maxServerNameLength = 64
+const maxServerDescriptionLength = 200
+
+var ErrInvalidServerText = errors.New("invalid server text")
+var ErrInvalidPassword = errors.New("password must be 1-32 characters long, and consist of letters, digits, or symbols without spaces")
+var ErrPublicNotPermitted = errors.New("you are not permitted to create servers without a password")
+var ErrPublicWithPassword = fmt.Errorf("cannot specify both %s, and %s", CreateServerPassword, CreateServerPublic)
+
+var passwordRegexp = regexp.MustCompile(`^[!-~]{1,32}$`)
+
+// sanitizeServerText strips control characters, and collapses whitespace of the text shown in the server browser
+func sanitizeServerText(option string, value string, maxLength int) (string, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, value)
+	cleaned = strings.Join(strings.Fields(cleaned), " ")
+	if cleaned == "" {
+		return "", fmt.Errorf("%w: %s can't be empty", ErrInvalidServerText, option)
+	}
+	if utf8.RuneCountInString(cleaned) > maxLength {
+		return "", fmt.Errorf("%w: %s can't be longer than %d characters", ErrInvalidServerText, option, maxLength)
+	}
+	return cleaned, nil
+}
+
+// serverIdentity is how the server presents itself to players
+type serverIdentity struct {
+	displayName string
+	description string
+	password    string
+	public      bool
+}
+
+func (h *handler) resolveServerIdentity(interaction *discordgo.InteractionCreate, flags flagResolver) (serverIdentity, error) {
+	var identity serverIdentity
+	var err error
+	if name, ok := flags.stringValue(CreateServerDisplayName); ok {
+		identity.displayName, err = sanitizeServerText(CreateServerDisplayName, name, maxServerNameLength)
+		if err != nil {
+			return serverIdentity{}, err
+		}
+	}
+	if description, ok := flags.stringValue(CreateServerDescription); ok {
+		identity.description, err = sanitizeServerText(CreateServerDescription, description, maxServerDescriptionLength)
+		if err != nil {
+			return serverIdentity{}, err
+		}
+	}
+
+	identity.password, _ = flags.stringValue(CreateServerPassword)
+	identity.public, _ = flags.boolValue(CreateServerPublic)
+	switch {
+	case identity.public && identity.password != "":
+		return serverIdentity{}, ErrPublicWithPassword
+	case identity.public:
+		g := h.guildOf(interaction)
+		if g == nil || !g.canCreatePublic(interaction.Member) {
+			return serverIdentity{}, ErrPublicNotPermitted
+		}
+	case identity.password != "" && !passwordRegexp.MatchString(identity.password):
+		return serverIdentity{}, ErrInvalidPassword
+	}
+	return identity, nil
+}
+
+// serverPassword formats the password of the server for display
+func serverPassword(server *nsserver.NSServer) string {
+	switch {
+	case server.Public:
+		return "none, the server is public"
+	case server.Pin == "":
+		return unknown
+	default:
+		return fmt.Sprintf("`%s`", server.Pin)
+	}
+}
@@ -0,0 +1,60 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/l1ghthouse/northstar-bootstrap/src/providers"
+)
+
+const BuildImage = "build_image"
+
+func buildImageCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        BuildImage,
+		Description: "Build a snapshot with the game files, that servers of the region boot from",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         CreateServerRegion,
+				Description:  "region to build the snapshot in",
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (h *handler) handleBuildImage(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	sendInteractionDeferred(session, interaction)
+
+	// images are shared by every discord server, so only admins of the default one can build them
+	if !h.isBotAdmin(interaction) {
+		editDeferredInteractionReply(session, interaction.Interaction, "Only admins of the discord server, that manages the bot can build images", nil)
+
+		return
+	}
+	builder, ok := h.p.(providers.ImageBuilder)
+	if !ok {
+		editDeferredInteractionReply(session, interaction.Interaction, "The provider doesn't support images", nil)
+
+		return
+	}
+
+	region, _ := optionValue(interaction.ApplicationCommandData().Options, CreateServerRegion)
+	editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("Building image in %s. This can take up to an hour, the result will be posted here", region.StringValue()), nil)
+
+	// building outlives the interaction token, so the result is posted as a message
+	go func(city string, builtBy string, channelID string) {
+		built, err := builder.BuildImage(context.Background(), city, builtBy)
+		if err != nil {
+			log.Printf("unable to build image in %s: %v", city, err)
+			sendMessage(session, channelID, fmt.Sprintf("<@%s> unable to build image in %s: %v", builtBy, city, err))
+
+			return
+		}
+		sendMessage(session, channelID, fmt.Sprintf("<@%s> image `%s` is ready, servers in %s now boot with the game files pre-baked", builtBy, built.SnapshotID, built.Region))
+	}(region.StringValue(), interaction.Member.User.ID, interaction.ChannelID)
+}
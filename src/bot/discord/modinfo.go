@@ -0,0 +1,132 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod"
+)
+
+const ModInfo = "mod_info"
+const ModInfoName = "mod"
+
+func modInfoCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        ModInfo,
+		Description: "Show conflicts, and requirements of the mods",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         ModInfoName,
+				Description:  "mod to show. Rules of all mods are shown when empty",
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func joinOrNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// modRules describes the relations of the mod, including the ones declared by other mods
+func modRules(name string) string {
+	r := mod.RelationsOf(name)
+	var requiredBy []string
+	var impliedBy []string
+	for _, other := range mod.Names() {
+		otherRelations := mod.RelationsOf(other)
+		for _, required := range otherRelations.Requires {
+			if required == name {
+				requiredBy = append(requiredBy, other)
+			}
+		}
+		for _, implied := range otherRelations.Implies {
+			if implied == name {
+				impliedBy = append(impliedBy, other)
+			}
+		}
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("Conflicts with: %s", joinOrNone(r.ConflictsWith)))
+	builder.WriteString("\n")
+	builder.WriteString(fmt.Sprintf("Requires: %s", joinOrNone(r.Requires)))
+	builder.WriteString("\n")
+	builder.WriteString(fmt.Sprintf("Implies: %s", joinOrNone(r.Implies)))
+	builder.WriteString("\n")
+	if len(requiredBy) > 0 {
+		builder.WriteString(fmt.Sprintf("Required by: %s", joinOrNone(requiredBy)))
+		builder.WriteString("\n")
+	}
+	if len(impliedBy) > 0 {
+		builder.WriteString(fmt.Sprintf("Enabled by: %s", joinOrNone(impliedBy)))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+func hasRules(name string) bool {
+	if r := mod.RelationsOf(name); len(r.ConflictsWith) > 0 || len(r.Requires) > 0 || len(r.Implies) > 0 {
+		return true
+	}
+	for _, other := range mod.Names() {
+		r := mod.RelationsOf(other)
+		for _, related := range append(r.Requires, r.Implies...) {
+			if related == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func modInfoEmbed(name string) (*discordgo.MessageEmbed, error) {
+	if name != "" {
+		generator, ok := mod.ByName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownMod, name)
+		}
+		return &discordgo.MessageEmbed{
+			Title:       name,
+			Description: fmt.Sprintf("Enabled by default: %t\n%s", generator().EnabledByDefault(), modRules(name)),
+		}, nil
+	}
+
+	embed := &discordgo.MessageEmbed{Title: "Mod rules"}
+	for _, modName := range mod.Names() {
+		if !hasRules(modName) {
+			continue
+		}
+		if len(embed.Fields) == maxEmbedFields {
+			embed.Description = fmt.Sprintf("Not all mods fit in the message. Use /%s %s:<mod> to see the rest", ModInfo, ModInfoName)
+			break
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: modName, Value: truncateField(modRules(modName))})
+	}
+	if len(embed.Fields) == 0 {
+		embed.Description = "None of the mods have conflicts, or requirements"
+	}
+	return embed, nil
+}
+
+func (h *handler) handleModInfo(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	sendInteractionDeferred(session, interaction)
+
+	name := ""
+	if val, ok := optionValue(interaction.ApplicationCommandData().Options, ModInfoName); ok {
+		name = strings.TrimSpace(val.StringValue())
+	}
+
+	embed, err := modInfoEmbed(name)
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
+		return
+	}
+	editDeferredInteractionEmbeds(session, interaction.Interaction, "", []*discordgo.MessageEmbed{embed}, nil, nil)
+}
@@ -17,36 +17,53 @@ import (
 )
 
 type Notifier struct {
-	discordClient                     *discordgo.Session
-	reportChannel                     string
+	discordClient *discordgo.Session
+	// reportChannels maps guild id to the channel used for reporting
+	reportChannels                    map[string]string
+	defaultGuildID                    string
 	RebalancedLTSRankingMongoDBString string
 }
 
-func NewNotifier(discordClient *discordgo.Session, reportChannel string, rebalancedLTSRankingMongoDBString string) *Notifier {
-	if reportChannel == "" && rebalancedLTSRankingMongoDBString == "" {
+func NewNotifier(discordClient *discordgo.Session, reportChannels map[string]string, defaultGuildID string, rebalancedLTSRankingMongoDBString string) *Notifier {
+	hasReportChannel := false
+	for _, channel := range reportChannels {
+		if channel != "" {
+			hasReportChannel = true
+		}
+	}
+	if !hasReportChannel && rebalancedLTSRankingMongoDBString == "" {
 		return nil
 	}
 	return &Notifier{
 		discordClient:                     discordClient,
-		reportChannel:                     reportChannel,
+		reportChannels:                    reportChannels,
+		defaultGuildID:                    defaultGuildID,
 		RebalancedLTSRankingMongoDBString: rebalancedLTSRankingMongoDBString,
 	}
 }
 
+// reportChannel returns report channel of the guild that owns the server
+func (d *Notifier) reportChannel(server *nsserver.NSServer) string {
+	if server.GuildID == "" {
+		return d.reportChannels[d.defaultGuildID]
+	}
+	return d.reportChannels[server.GuildID]
+}
+
 func (d *Notifier) NotifyServer(server *nsserver.NSServer, message string) {
-	if d.reportChannel != "" {
-		sendMessage(d.discordClient, d.reportChannel, fmt.Sprintf("Server %s:\n", server.Name)+message)
+	if reportChannel := d.reportChannel(server); reportChannel != "" {
+		sendMessage(d.discordClient, reportChannel, fmt.Sprintf("Server %s:\n", server.Name)+message)
 	}
 }
 
 func (d *Notifier) NotifyAndAttachServerData(server *nsserver.NSServer, message string, filename string, file *bytes.Buffer) {
-	if d.reportChannel != "" {
+	if reportChannel := d.reportChannel(server); reportChannel != "" {
 		if file != nil {
 			if d.RebalancedLTSRankingMongoDBString != "" {
 				buffer := bytes.NewBuffer(file.Bytes())
 				go d.processRebalancedLTSLogs(*server, d.RebalancedLTSRankingMongoDBString, buffer)
 			}
-			sendComplexMessage(d.discordClient, d.reportChannel, fmt.Sprintf("Server %s:\n", server.Name)+message, []*discordgo.File{{
+			sendComplexMessage(d.discordClient, reportChannel, fmt.Sprintf("Server %s:\n", server.Name)+message, []*discordgo.File{{
 				Name:        filename,
 				ContentType: "application/octet-stream",
 				Reader:      file,
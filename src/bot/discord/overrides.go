@@ -0,0 +1,266 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod"
+	"github.com/l1ghthouse/northstar-bootstrap/src/override"
+)
+
+const overrideSourceConfig = "config"
+const overrideSourceDB = "database"
+
+// commandOverrides holds command flag overrides of a guild from the config file, and the ones set at runtime.
+// Runtime overrides are persisted in the database, and take precedence over the config file.
+type commandOverrides struct {
+	lock      *sync.RWMutex
+	guildID   string
+	isDefault bool
+	config    []CommandOverrides
+	stored    []CommandOverrides
+	repo      override.Repo
+}
+
+func newCommandOverrides(ctx context.Context, guildID string, isDefault bool, config []CommandOverrides, repo override.Repo) (*commandOverrides, error) {
+	c := &commandOverrides{
+		lock:      &sync.RWMutex{},
+		guildID:   guildID,
+		isDefault: isDefault,
+		config:    config,
+		repo:      repo,
+	}
+	for _, o := range config {
+		if err := validateCommandOverride(o.Command, o.Flag, o.Value); err != nil {
+			return nil, fmt.Errorf("invalid command override in config: %w", err)
+		}
+	}
+	if err := c.reload(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *commandOverrides) reload(ctx context.Context) error {
+	overrides, err := c.repo.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load command overrides: %w", err)
+	}
+	stored := make([]CommandOverrides, 0, len(overrides))
+	for _, o := range overrides {
+		// overrides stored before multi guild support belong to the default guild
+		if o.GuildID != c.guildID && (o.GuildID != "" || !c.isDefault) {
+			continue
+		}
+		stored = append(stored, CommandOverrides{
+			Command: o.Command,
+			Flag:    o.Flag,
+			Value:   o.Value,
+		})
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stored = stored
+	return nil
+}
+
+func (c *commandOverrides) lookup(command, flag string) (string, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for _, list := range [][]CommandOverrides{c.stored, c.config} {
+		for _, commandOverride := range list {
+			if command == commandOverride.Command && flag == commandOverride.Flag {
+				return commandOverride.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (c *commandOverrides) set(ctx context.Context, command, flag, value, updatedBy string) error {
+	if err := validateCommandOverride(command, flag, value); err != nil {
+		return err
+	}
+	err := c.repo.Store(ctx, &override.Override{
+		GuildID:   c.guildID,
+		Command:   command,
+		Flag:      flag,
+		Value:     value,
+		UpdatedBy: updatedBy,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to save command override: %w", err)
+	}
+	return c.reload(ctx)
+}
+
+func (c *commandOverrides) unset(ctx context.Context, command, flag string) error {
+	err := c.repo.Delete(ctx, c.guildID, command, flag)
+	if err != nil && c.isDefault {
+		if legacyErr := c.repo.Delete(ctx, "", command, flag); legacyErr == nil {
+			err = nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("unable to delete command override: %w", err)
+	}
+	return c.reload(ctx)
+}
+
+func (c *commandOverrides) inConfig(command, flag string) (string, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for _, commandOverride := range c.config {
+		if command == commandOverride.Command && flag == commandOverride.Flag {
+			return commandOverride.Value, true
+		}
+	}
+	return "", false
+}
+
+// String formats effective overrides, along with the place they are defined in
+func (c *commandOverrides) String() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	builder := strings.Builder{}
+	for _, o := range c.stored {
+		builder.WriteString(fmt.Sprintf("/%s %s=`%s` (%s)\n", o.Command, o.Flag, o.Value, overrideSourceDB))
+	}
+	for _, o := range c.config {
+		shadowed := false
+		for _, s := range c.stored {
+			if s.Command == o.Command && s.Flag == o.Flag {
+				shadowed = true
+				break
+			}
+		}
+		if !shadowed {
+			builder.WriteString(fmt.Sprintf("/%s %s=`%s` (%s)\n", o.Command, o.Flag, o.Value, overrideSourceConfig))
+		}
+	}
+	return builder.String()
+}
+
+var ErrInvalidOverride = errors.New("invalid command override")
+
+// validateCommandOverride checks that the flag is an optional option of the command, and that the value can be
+// interpreted as the option type.
+func validateCommandOverride(command, flag, value string) error {
+	for _, c := range applicationCommands() {
+		if c.Name != command {
+			continue
+		}
+		for _, option := range c.Options {
+			if option.Name != flag {
+				continue
+			}
+			if option.Required {
+				return fmt.Errorf("%w: /%s %s is a required option, and can't be overridden", ErrInvalidOverride, command, flag)
+			}
+			return validateOptionValue(option, value)
+		}
+		// mods aren't create_server options, but can still be enabled by default
+		if _, ok := mod.ByName[flag]; ok && command == CreateServer {
+			return validateOptionValue(&discordgo.ApplicationCommandOption{Name: flag, Type: discordgo.ApplicationCommandOptionBoolean}, value)
+		}
+		return fmt.Errorf("%w: /%s doesn't have %s option", ErrInvalidOverride, command, flag)
+	}
+	return fmt.Errorf("%w: unknown command /%s", ErrInvalidOverride, command)
+}
+
+func validateOptionValue(option *discordgo.ApplicationCommandOption, value string) error {
+	var err error
+	switch option.Type {
+	case discordgo.ApplicationCommandOptionBoolean:
+		_, err = strconv.ParseBool(value)
+	case discordgo.ApplicationCommandOptionInteger:
+		var parsed int64
+		if parsed, err = strconv.ParseInt(value, 10, 64); err == nil {
+			if err := optionInRange(option, float64(parsed)); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidOverride, err)
+			}
+		}
+	case discordgo.ApplicationCommandOptionNumber:
+		var parsed float64
+		if parsed, err = strconv.ParseFloat(value, 64); err == nil {
+			if err := optionInRange(option, parsed); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidOverride, err)
+			}
+		}
+	case discordgo.ApplicationCommandOptionString:
+	default:
+		return fmt.Errorf("%w: %s options can't be overridden", ErrInvalidOverride, option.Type.String())
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s is not a valid %s value for %s", ErrInvalidOverride, value, option.Type.String(), option.Name)
+	}
+
+	if len(option.Choices) == 0 {
+		return nil
+	}
+	choices := make([]string, len(option.Choices))
+	for idx, choice := range option.Choices {
+		choices[idx] = fmt.Sprintf("%v", choice.Value)
+		if choices[idx] == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not one of the choices for %s: %s", ErrInvalidOverride, value, option.Name, strings.Join(choices, ", "))
+}
+
+func (h *handler) handleCommandFlagOverrides(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	sendInteractionDeferred(session, interaction)
+	overrides := h.guildOf(interaction).overrides.String()
+	if overrides == "" {
+		editDeferredInteractionReply(session, interaction.Interaction, "No command flag overrides", nil)
+		return
+	}
+	editDeferredInteractionReply(session, interaction.Interaction, overrides, nil)
+}
+
+func (h *handler) handleOverrides(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	ctx := context.Background()
+	sendInteractionDeferred(session, interaction)
+
+	if !h.isAdmin(interaction) {
+		editDeferredInteractionReply(session, interaction.Interaction, "You need the Manage Server permission, or an admin role to change command flag overrides", nil)
+
+		return
+	}
+
+	subCommand := interaction.ApplicationCommandData().Options[0]
+	command, _ := optionValue(subCommand.Options, OverridesCommand)
+	flag, _ := optionValue(subCommand.Options, OverridesFlag)
+
+	switch subCommand.Name {
+	case OverridesSet:
+		value, _ := optionValue(subCommand.Options, OverridesValue)
+		err := h.guildOf(interaction).overrides.set(ctx, command.StringValue(), flag.StringValue(), value.StringValue(), interaction.Member.User.ID)
+		if err != nil {
+			editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to set override: %v", err), nil)
+
+			return
+		}
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("/%s %s now defaults to `%s`", command.StringValue(), flag.StringValue(), value.StringValue()), nil)
+	case OverridesUnset:
+		err := h.guildOf(interaction).overrides.unset(ctx, command.StringValue(), flag.StringValue())
+		if err != nil {
+			editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to unset override: %v", err), nil)
+
+			return
+		}
+		message := fmt.Sprintf("removed override for /%s %s", command.StringValue(), flag.StringValue())
+		if value, ok := h.guildOf(interaction).overrides.inConfig(command.StringValue(), flag.StringValue()); ok {
+			message += fmt.Sprintf(". The override from the config file still applies: `%s`", value)
+		}
+		editDeferredInteractionReply(session, interaction.Interaction, message, nil)
+	default:
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unknown subcommand: %s", subCommand.Name), nil)
+	}
+}
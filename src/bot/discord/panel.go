@@ -0,0 +1,458 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gofrs/uuid"
+	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+	"github.com/l1ghthouse/northstar-bootstrap/src/providers/util"
+)
+
+// Components carry the action, and the server id in their custom id: server:<action>:<server id>
+const serverComponentPrefix = "server"
+const (
+	ServerActionRestart       = "restart"
+	ServerActionExtend        = "extend"
+	ServerActionLogs          = "logs"
+	ServerActionDelete        = "delete"
+	ServerActionDeleteConfirm = "delete_confirm"
+	ServerActionConnect       = "connect"
+	ServerActionSelect        = "select"
+)
+
+const extendDurationInput = "duration"
+
+// maxEmbedsPerMessage is the maximum number of embeds discord accepts in a single message
+const maxEmbedsPerMessage = 10
+const maxEmbedFieldLength = 1024
+
+func serverComponentID(action string, serverID uuid.UUID) string {
+	return fmt.Sprintf("%s:%s:%s", serverComponentPrefix, action, serverID.String())
+}
+
+var ErrInvalidComponentID = errors.New("invalid component id")
+
+func parseServerComponentID(customID string) (string, uuid.UUID, error) {
+	parts := strings.SplitN(customID, ":", 3)
+	if len(parts) != 3 || parts[0] != serverComponentPrefix {
+		return "", uuid.Nil, fmt.Errorf("%w: %s", ErrInvalidComponentID, customID)
+	}
+	id, err := uuid.FromString(parts[2])
+	if err != nil {
+		return "", uuid.Nil, fmt.Errorf("%w: %s", ErrInvalidComponentID, customID)
+	}
+	return parts[1], id, nil
+}
+
+// installedMods returns mods installed on the server. Only names of the enabled mods are known for servers created
+// before installed mods were recorded.
+func installedMods(server *nsserver.NSServer) nsserver.InstalledMods {
+	if len(server.InstalledMods) > 0 {
+		return server.InstalledMods
+	}
+	var names []string
+	for modName, value := range server.ModOptions {
+		if strings.HasSuffix(modName, util.RequiredByClientPostfix) {
+			continue
+		}
+		if enabled, ok := value.(bool); ok && enabled {
+			names = append(names, modName)
+		}
+	}
+	sort.Strings(names)
+
+	mods := make(nsserver.InstalledMods, len(names))
+	for idx, name := range names {
+		mods[idx] = nsserver.InstalledMod{Name: name}
+	}
+	return mods
+}
+
+func truncateField(value string) string {
+	if len(value) > maxEmbedFieldLength {
+		return value[:maxEmbedFieldLength-3] + "..."
+	}
+	return value
+}
+
+func (h *handler) deleteAt(server *nsserver.NSServer) (time.Time, bool) {
+	if h.autoDeleteDuration <= time.Duration(0) {
+		return time.Time{}, false
+	}
+	autoDelete := h.autoDeleteDuration
+	if server.ExtendLifetime != nil {
+		autoDelete += *server.ExtendLifetime
+	}
+	return server.CreatedAt.Add(autoDelete), true
+}
+
+func (h *handler) serverEmbed(server *nsserver.NSServer) *discordgo.MessageEmbed {
+	user := unknown
+	if server.RequestedBy != "" {
+		user = fmt.Sprintf("<@%s>", server.RequestedBy)
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Region", Value: valueOrUnknown(server.Region), Inline: true},
+		{Name: "Password", Value: serverPassword(server), Inline: true},
+		{Name: "Server Version", Value: valueOrUnknown(server.ServerVersion), Inline: true},
+		{Name: "Requested by", Value: user, Inline: true},
+	}
+	if deleteAt, ok := h.deleteAt(server); ok {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Deleted", Value: fmt.Sprintf("<t:%d:R>", deleteAt.Unix()), Inline: true})
+	}
+	if server.MasterServer != "" && server.MasterServer != DefaultMasterServer {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Master server", Value: server.MasterServer, Inline: true})
+	}
+	if server.Host != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Host", Value: fmt.Sprintf("%s, port %d", server.Host, server.GameUDPPort), Inline: true})
+	}
+	if server.DisplayName != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Listed as", Value: server.DisplayName})
+	}
+	if server.Description != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Description", Value: truncateField(server.Description)})
+	}
+	if gameArgs := util.GameArgs(server); gameArgs != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Game", Value: fmt.Sprintf("`%s`", gameArgs)})
+	}
+	if server.Insecure {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Insecure", Value: fmt.Sprintf("`connect %s:%d`", server.MainIP, server.GameUDPPort)})
+	}
+	if mods := installedMods(server); len(mods) > 0 {
+		versions := make([]string, 0, len(mods))
+		var clientRequired []string
+		for _, m := range mods {
+			if m.Version != "" {
+				versions = append(versions, fmt.Sprintf("%s (%s)", m.Name, m.Version))
+			} else {
+				versions = append(versions, m.Name)
+			}
+			if m.RequiredByClient {
+				clientRequired = append(clientRequired, fmt.Sprintf("%s: <%s>", m.Name, m.DownloadLink))
+			}
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Mods", Value: truncateField(strings.Join(versions, "\n"))})
+		if len(clientRequired) > 0 {
+			fields = append(fields, &discordgo.MessageEmbedField{Name: "Required to be downloaded by client", Value: truncateField(strings.Join(clientRequired, "\n"))})
+		}
+	}
+	if len(server.ModConVars) > 0 {
+		conVars := make([]string, 0, len(server.ModConVars))
+		for key, value := range server.ModConVars {
+			conVars = append(conVars, fmt.Sprintf("`%s=%v`", key, value))
+		}
+		sort.Strings(conVars)
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Mod ConVars", Value: truncateField(strings.Join(conVars, "\n"))})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:  server.Name,
+		Fields: fields,
+	}
+}
+
+// valueOrUnknown replaces empty values of servers missing from the database, since discord rejects empty fields
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return unknown
+	}
+	return value
+}
+
+func serverControls(server *nsserver.NSServer) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Show Connect Info", Style: discordgo.SuccessButton, CustomID: serverComponentID(ServerActionConnect, server.ID)},
+				discordgo.Button{Label: "Restart", Style: discordgo.PrimaryButton, CustomID: serverComponentID(ServerActionRestart, server.ID)},
+				discordgo.Button{Label: "Extend", Style: discordgo.SecondaryButton, CustomID: serverComponentID(ServerActionExtend, server.ID)},
+				discordgo.Button{Label: "Get Logs", Style: discordgo.SecondaryButton, CustomID: serverComponentID(ServerActionLogs, server.ID)},
+				discordgo.Button{Label: "Delete", Style: discordgo.DangerButton, CustomID: serverComponentID(ServerActionDelete, server.ID)},
+			},
+		},
+	}
+}
+
+// serverSelect lets user pick one of the listed servers, to open its control panel
+func serverSelect(servers []*nsserver.NSServer) []discordgo.MessageComponent {
+	options := make([]discordgo.SelectMenuOption, 0, len(servers))
+	for _, server := range servers {
+		if server.ID == uuid.Nil {
+			continue
+		}
+		options = append(options, discordgo.SelectMenuOption{
+			Label:       server.Name,
+			Value:       server.ID.String(),
+			Description: server.Region,
+		})
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    fmt.Sprintf("%s:%s:%s", serverComponentPrefix, ServerActionSelect, uuid.Nil.String()),
+					Placeholder: "Manage server",
+					Options:     options,
+				},
+			},
+		},
+	}
+}
+
+func (h *handler) connectInfo(server *nsserver.NSServer) string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("Server: **%s**", server.Name))
+	builder.WriteString("\n")
+	builder.WriteString(fmt.Sprintf("Password: %s", serverPassword(server)))
+	builder.WriteString("\n")
+	if server.Insecure {
+		builder.WriteString(fmt.Sprintf("If master server is offline, use: `connect %s:%d`", server.MainIP, server.GameUDPPort))
+		builder.WriteString("\n")
+	}
+	if server.MasterServer != "" && server.MasterServer != DefaultMasterServer {
+		builder.WriteString(fmt.Sprintf("Master server: %s", server.MasterServer))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+func (h *handler) deleteServer(ctx context.Context, server *nsserver.NSServer) error {
+	if h.notifier != nil {
+		logs, err := h.p.ExtractServerLogs(ctx, server)
+		if err != nil {
+			log.Println(fmt.Sprintf("unable to extract logs for server: %v", err))
+			h.notifier.NotifyServer(server, fmt.Sprintf("unable to extract logs for server: %v", err))
+		} else {
+			go h.notifier.NotifyAndAttachServerData(server, "Deleted, logs:", fmt.Sprintf("%s.log.zip", server.Name), logs)
+		}
+	}
+
+	err := h.p.DeleteServer(ctx, server)
+	if err != nil {
+		return fmt.Errorf("failed to delete the target server. error: %w", err)
+	}
+
+	err = h.nsRepo.DeleteByName(ctx, server.Name)
+	if err != nil {
+		log.Println(fmt.Sprintf("unable to delete server from the database: %v", err))
+	}
+	h.statusBoard.requestRefresh()
+	return nil
+}
+
+func (h *handler) extendServer(ctx context.Context, interaction *discordgo.InteractionCreate, server *nsserver.NSServer, extend time.Duration) error {
+	if extend <= 0 {
+		return fmt.Errorf("duration should not be negative, or 0")
+	}
+
+	if server.ExtendLifetime != nil {
+		extend += *server.ExtendLifetime
+	}
+
+	maxExtendDuration := h.guildOf(interaction).maxExtendDuration
+	if extend > maxExtendDuration {
+		return fmt.Errorf("extended lifetime exceeded maximum allowed extended duration. Extended duration: %s, Max extended duration: %s", extend.String(), maxExtendDuration.String())
+	}
+
+	server.ExtendLifetime = &extend
+	err := h.nsRepo.Update(ctx, server)
+	if err != nil {
+		return fmt.Errorf("Failed to update ExtendLifetime field in database, error: %w", err)
+	}
+	h.statusBoard.requestRefresh()
+	return nil
+}
+
+func (h *handler) sendLogs(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, server *nsserver.NSServer) error {
+	file, err := h.p.ExtractServerLogs(ctx, server)
+	if err != nil {
+		return fmt.Errorf("failed to extract logs from target server. error: %w", err)
+	}
+
+	files := []*discordgo.File{{
+		Name:        fmt.Sprintf("%s.log.zip", server.Name),
+		ContentType: "application/octet-stream",
+		Reader:      file,
+	}}
+
+	go sendMessageWithFilesDM(session, interaction.Member.User.ID, fmt.Sprintf("logs extracted from server %s", server.Name), files)
+	return nil
+}
+
+// guildServerByID returns server from the database, if it belongs to the guild of the interaction
+func (h *handler) guildServerByID(ctx context.Context, interaction *discordgo.InteractionCreate, id uuid.UUID) (*nsserver.NSServer, error) {
+	server, err := h.nsRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server from cache database. error: %w", err)
+	}
+	if !h.ownsServer(interaction.GuildID, server) {
+		return nil, fmt.Errorf("%w: %s", ErrServerNotOwned, server.Name)
+	}
+	return server, nil
+}
+
+func (h *handler) handleServerComponent(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	ctx := context.Background()
+	data := interaction.MessageComponentData()
+	action, serverID, err := parseServerComponentID(data.CustomID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if action == ServerActionSelect && len(data.Values) > 0 {
+		serverID, err = uuid.FromString(data.Values[0])
+		if err != nil {
+			log.Println(fmt.Sprintf("invalid server id in select menu: %v", err))
+			return
+		}
+	}
+
+	// extend asks for the duration in a modal, which has to be the first response to the interaction
+	if action == ServerActionExtend {
+		h.sendExtendModal(session, interaction, serverID)
+		return
+	}
+
+	sendInteractionDeferredEphemeral(session, interaction)
+
+	server, err := h.guildServerByID(ctx, interaction, serverID)
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
+		return
+	}
+
+	switch action {
+	case ServerActionSelect:
+		editDeferredInteractionEmbeds(session, interaction.Interaction, "", []*discordgo.MessageEmbed{h.serverEmbed(server)}, serverControls(server), nil)
+	case ServerActionConnect:
+		editDeferredInteractionReply(session, interaction.Interaction, h.connectInfo(server), nil)
+	case ServerActionRestart:
+		err = h.p.RestartServer(ctx, server)
+		if err != nil {
+			editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("failed to restart the target server. error: %v", err), nil)
+
+			return
+		}
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("restarted server %s", server.Name), nil)
+	case ServerActionLogs:
+		err = h.sendLogs(ctx, session, interaction, server)
+		if err != nil {
+			editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
+			return
+		}
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("logs extraction for server %s is completed, and are sent privately to you", server.Name), nil)
+	case ServerActionDelete:
+		editDeferredInteractionEmbeds(session, interaction.Interaction, fmt.Sprintf("Are you sure you want to delete **%s**?", server.Name), nil, []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "Delete", Style: discordgo.DangerButton, CustomID: serverComponentID(ServerActionDeleteConfirm, server.ID)},
+				},
+			},
+		}, nil)
+	case ServerActionDeleteConfirm:
+		err = h.deleteServer(ctx, server)
+		if err != nil {
+			editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
+			return
+		}
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("deleted server %s", server.Name), nil)
+	default:
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unknown action: %s", action), nil)
+	}
+}
+
+func (h *handler) sendExtendModal(session *discordgo.Session, interaction *discordgo.InteractionCreate, serverID uuid.UUID) {
+	if err := session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: serverComponentID(ServerActionExtend, serverID),
+			Title:    "Extend server lifetime",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    extendDurationInput,
+							Label:       "Duration. Ex: 1h, 30m, 1h30m50s",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "30m",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+	}); err != nil {
+		log.Println("Error sending modal: ", err)
+	}
+}
+
+// modalValue returns value of the text input with given custom id
+func modalValue(data discordgo.ModalSubmitInteractionData, customID string) string {
+	for _, row := range data.Components {
+		actionsRow, ok := row.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, component := range actionsRow.Components {
+			if input, ok := component.(*discordgo.TextInput); ok && input.CustomID == customID {
+				return input.Value
+			}
+		}
+	}
+	return ""
+}
+
+func (h *handler) handleServerModal(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	ctx := context.Background()
+	data := interaction.ModalSubmitData()
+	action, serverID, err := parseServerComponentID(data.CustomID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	sendInteractionDeferredEphemeral(session, interaction)
+
+	if action != ServerActionExtend {
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unknown action: %s", action), nil)
+
+		return
+	}
+
+	extend, err := time.ParseDuration(strings.TrimSpace(modalValue(data, extendDurationInput)))
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("failed to parse duration. error: %v", err), nil)
+
+		return
+	}
+
+	server, err := h.guildServerByID(ctx, interaction, serverID)
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
+		return
+	}
+
+	err = h.extendServer(ctx, interaction, server, extend)
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
+		return
+	}
+
+	editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("server lifetime successfully updated to: %s", server.ExtendLifetime), nil)
+}
@@ -0,0 +1,261 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod"
+	"github.com/l1ghthouse/northstar-bootstrap/src/preset"
+)
+
+func presetScopeOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        PresetScope,
+		Description: "Whether the preset is personal, or shared with the whole discord server. Defaults to personal",
+		Choices: []*discordgo.ApplicationCommandOptionChoice{
+			{Name: preset.ScopeUser, Value: preset.ScopeUser},
+			{Name: preset.ScopeGuild, Value: preset.ScopeGuild},
+		},
+	}
+}
+
+var ErrUnknownMod = errors.New("unknown mod")
+
+// parseModList parses comma separated list of mods, where a mod prefixed with "-" is explicitly disabled
+func parseModList(mods string) (map[string]bool, error) {
+	parsed := make(map[string]bool)
+	for _, m := range strings.Split(mods, ",") {
+		modName := strings.TrimSpace(m)
+		if modName == "" {
+			continue
+		}
+		enabled := !strings.HasPrefix(modName, "-")
+		modName = strings.TrimPrefix(modName, "-")
+		if _, ok := mod.ByName[modName]; !ok {
+			return nil, fmt.Errorf("%w: %s. Thunderstore mods should be passed with /%s", ErrUnknownMod, modName, CreateServerCustomThunderstoreMods)
+		}
+		parsed[modName] = enabled
+	}
+	return parsed, nil
+}
+
+func presetScopeUserID(options []*discordgo.ApplicationCommandInteractionDataOption, interaction *discordgo.InteractionCreate) (string, string) {
+	scope := preset.ScopeUser
+	if val, ok := optionValue(options, PresetScope); ok {
+		scope = val.StringValue()
+	}
+	if scope == preset.ScopeGuild {
+		return scope, ""
+	}
+	return scope, interaction.Member.User.ID
+}
+
+func formatPresetOptions(options map[string]interface{}) string {
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, len(keys))
+	for idx, k := range keys {
+		values[idx] = fmt.Sprintf("%s=%v", k, options[k])
+	}
+	return strings.Join(values, ", ")
+}
+
+func (h *handler) handlePreset(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	sendInteractionDeferred(session, interaction)
+
+	subCommand := interaction.ApplicationCommandData().Options[0]
+	switch subCommand.Name {
+	case PresetSave:
+		h.handlePresetSave(session, interaction, subCommand.Options)
+	case PresetList:
+		h.handlePresetList(session, interaction)
+	case PresetDelete:
+		h.handlePresetDelete(session, interaction, subCommand.Options)
+	default:
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unknown subcommand: %s", subCommand.Name), nil)
+	}
+}
+
+func (h *handler) handlePresetSave(session *discordgo.Session, interaction *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	ctx := context.Background()
+	name, _ := optionValue(options, PresetName)
+	scope, userID := presetScopeUserID(options, interaction)
+
+	if scope == preset.ScopeGuild && !h.isAdmin(interaction) {
+		editDeferredInteractionReply(session, interaction.Interaction, "You need the Manage Server permission, or an admin role to save discord server wide presets", nil)
+
+		return
+	}
+
+	presetOptions := make(map[string]interface{})
+	for _, option := range options {
+		switch option.Name {
+		case PresetName, PresetScope:
+			continue
+		case PresetMods:
+			mods, err := parseModList(option.StringValue())
+			if err != nil {
+				editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to parse mods: %v", err), nil)
+
+				return
+			}
+			for modName, enabled := range mods {
+				presetOptions[modName] = enabled
+			}
+		case CreateServerModConfig:
+			if _, err := mod.ParseConVars(option.StringValue()); err != nil {
+				editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to parse mod config: %v", err), nil)
+
+				return
+			}
+			presetOptions[option.Name] = option.Value
+		case CreateServerTickRate:
+			tickRate := option.UintValue()
+			if tickRate < 20 || tickRate > 120 {
+				editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("tick_rate must be between 20, and 120. Following value is not supported: %d", tickRate), nil)
+
+				return
+			}
+			presetOptions[option.Name] = tickRate
+		case CreateServerPlaylist, CreateServerMap, CreateServerMode:
+			// mods aren't known until the server is created, so the value only has to be provided by any of them
+			var err error
+			switch option.Name {
+			case CreateServerPlaylist:
+				err = mod.ValidateGameOptions(option.StringValue(), "", "", nil)
+			case CreateServerMap:
+				err = mod.ValidateGameOptions("", option.StringValue(), "", nil)
+			default:
+				err = mod.ValidateGameOptions("", "", option.StringValue(), nil)
+			}
+			if err != nil {
+				editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
+				return
+			}
+			presetOptions[option.Name] = option.Value
+		case CreateServerDisplayName, CreateServerDescription:
+			maxLength := maxServerNameLength
+			if option.Name == CreateServerDescription {
+				maxLength = maxServerDescriptionLength
+			}
+			value, err := sanitizeServerText(option.Name, option.StringValue(), maxLength)
+			if err != nil {
+				editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
+				return
+			}
+			presetOptions[option.Name] = value
+		case CreateServerMaxPlayers, CreateServerScoreLimit, CreateServerTimeLimit:
+			value := option.UintValue()
+			if err := validateGameLimit(option.Name, value); err != nil {
+				editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
+				return
+			}
+			presetOptions[option.Name] = value
+		default:
+			presetOptions[option.Name] = option.Value
+		}
+	}
+
+	if len(presetOptions) == 0 {
+		editDeferredInteractionReply(session, interaction.Interaction, "preset must contain at least one option", nil)
+
+		return
+	}
+
+	p := &preset.Preset{
+		Name:      name.StringValue(),
+		GuildID:   interaction.GuildID,
+		UserID:    userID,
+		CreatedBy: interaction.Member.User.ID,
+		Options:   presetOptions,
+	}
+
+	err := h.presetRepo.Store(ctx, p)
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to save preset: %v", err), nil)
+
+		return
+	}
+
+	editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("saved %s preset **%s**: %s", scope, p.Name, formatPresetOptions(presetOptions)), nil)
+}
+
+func (h *handler) handlePresetList(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	ctx := context.Background()
+
+	presets, err := h.presetRepo.List(ctx, interaction.GuildID, interaction.Member.User.ID)
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to list presets: %v", err), nil)
+
+		return
+	}
+
+	if len(presets) == 0 {
+		editDeferredInteractionReply(session, interaction.Interaction, "No presets saved", nil)
+
+		return
+	}
+
+	builder := strings.Builder{}
+	for _, p := range presets {
+		builder.WriteString(fmt.Sprintf("**%s** (%s): `%s`", p.Name, p.Scope(), formatPresetOptions(p.Options)))
+		builder.WriteString("\n")
+	}
+
+	message := builder.String()
+	var files []*discordgo.File
+	if len(message) > 1900 {
+		files = []*discordgo.File{{
+			Name:        "presets.txt",
+			ContentType: "application/octet-stream",
+			Reader:      strings.NewReader(message),
+		}}
+
+		message = "List of presets is too long to be sent in a message. Sending as a file instead."
+	}
+
+	editDeferredInteractionReply(session, interaction.Interaction, message, files)
+}
+
+func (h *handler) handlePresetDelete(session *discordgo.Session, interaction *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	ctx := context.Background()
+	name, _ := optionValue(options, PresetName)
+	scope, userID := presetScopeUserID(options, interaction)
+
+	if scope == preset.ScopeGuild && !h.isAdmin(interaction) {
+		presets, err := h.presetRepo.List(ctx, interaction.GuildID, interaction.Member.User.ID)
+		if err != nil {
+			editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to list presets: %v", err), nil)
+
+			return
+		}
+		for _, p := range presets {
+			if p.Name == name.StringValue() && p.Scope() == preset.ScopeGuild && p.CreatedBy != interaction.Member.User.ID {
+				editDeferredInteractionReply(session, interaction.Interaction, "Only the creator of the preset, or admins can delete discord server wide presets", nil)
+
+				return
+			}
+		}
+	}
+
+	err := h.presetRepo.DeleteByName(ctx, interaction.GuildID, userID, name.StringValue())
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to delete %s preset %s: %v", scope, name.StringValue(), err), nil)
+
+		return
+	}
+
+	editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("deleted %s preset %s", scope, name.StringValue()), nil)
+}
@@ -0,0 +1,103 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod/thunderstore"
+	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+)
+
+const ClientProfile = "client_profile"
+
+var ErrNoClientMods = errors.New("server has no client required thunderstore mods")
+
+// clientProfileMods returns client required thunderstore mods installed on the server, along with their dependencies
+func clientProfileMods(server *nsserver.NSServer) ([]thunderstore.ProfileMod, error) {
+	var mods []thunderstore.ProfileMod
+	added := make(map[string]bool)
+	for _, m := range server.InstalledMods {
+		if !m.RequiredByClient || m.ThunderstorePackage == "" {
+			continue
+		}
+		for _, dependencyString := range m.Dependencies {
+			dependency, err := thunderstore.ParseDependency(dependencyString)
+			if err != nil {
+				return nil, err
+			}
+			pkg := dependency.Owner + "-" + dependency.Name
+			if !added[pkg] {
+				added[pkg] = true
+				mods = append(mods, thunderstore.ProfileMod{Package: pkg, Version: dependency.Version})
+			}
+		}
+		if !added[m.ThunderstorePackage] {
+			added[m.ThunderstorePackage] = true
+			mods = append(mods, thunderstore.ProfileMod{Package: m.ThunderstorePackage, Version: m.Version})
+		}
+	}
+	return mods, nil
+}
+
+// clientProfile returns r2modman profile with client required mods of the server. ErrNoClientMods is returned, when
+// clients don't need any thunderstore mods to join.
+func clientProfile(server *nsserver.NSServer) (*discordgo.File, error) {
+	mods, err := clientProfileMods(server)
+	if err != nil {
+		return nil, err
+	}
+	if len(mods) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoClientMods, server.Name)
+	}
+	profile, err := thunderstore.ExportProfile(server.Name, mods)
+	if err != nil {
+		return nil, fmt.Errorf("unable to export client profile: %w", err)
+	}
+	return &discordgo.File{
+		Name:        fmt.Sprintf("%s.r2z", server.Name),
+		ContentType: "application/octet-stream",
+		Reader:      bytes.NewReader(profile),
+	}, nil
+}
+
+func clientProfileCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        ClientProfile,
+		Description: "r2modman profile with the mods clients need to join the server",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         ServerNameOpt,
+				Description:  "server name to export the client profile of",
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (h *handler) handleClientProfile(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	ctx := context.Background()
+	serverName := interaction.ApplicationCommandData().Options[0].StringValue()
+
+	sendInteractionDeferred(session, interaction)
+
+	server, err := h.guildServer(ctx, interaction, serverName)
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("failed to get server from cache database. error: %v", err), nil)
+
+		return
+	}
+
+	file, err := clientProfile(server)
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
+		return
+	}
+
+	editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("Import the profile in r2modman from the profile selection screen with Import / Update > From file, to join %s", server.Name), []*discordgo.File{file})
+}
@@ -0,0 +1,259 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod/thunderstore"
+	"github.com/l1ghthouse/northstar-bootstrap/src/preset"
+)
+
+const SearchMods = "search_mods"
+const SearchModsQuery = "query"
+
+// maxSearchResults fits every result button in a single row
+const maxSearchResults = 5
+
+// Components carry the action, and the thunderstore package in their custom id: mod:<action>:<Owner/Name>
+const modComponentPrefix = "mod"
+const ModActionAddToPreset = "add_to_preset"
+
+const presetNameInput = "preset"
+
+// maxCustomIDLength is the maximum length of the component custom id discord accepts
+const maxCustomIDLength = 100
+
+func modComponentID(action string, packageName string) string {
+	return fmt.Sprintf("%s:%s:%s", modComponentPrefix, action, packageName)
+}
+
+func isModComponentID(customID string) bool {
+	return strings.HasPrefix(customID, modComponentPrefix+":")
+}
+
+func parseModComponentID(customID string) (string, string, error) {
+	parts := strings.SplitN(customID, ":", 3)
+	if len(parts) != 3 || parts[0] != modComponentPrefix || parts[2] == "" {
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidComponentID, customID)
+	}
+	return parts[1], parts[2], nil
+}
+
+func searchModsCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        SearchMods,
+		Description: "Search thunderstore mods by name, owner, description and category",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        SearchModsQuery,
+				Description: "words to search for",
+				Required:    true,
+			},
+		},
+	}
+}
+
+func packageEmbed(pkg thunderstore.Package) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: pkg.Owner + "/" + pkg.Name,
+		URL:   pkg.PackageURL,
+	}
+
+	latest := unknown
+	if version, err := thunderstore.GetLatestPackageVersion(pkg); err == nil {
+		latest = version.VersionNumber
+		embed.Description = version.Description
+		if version.Icon != "" {
+			embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: version.Icon}
+		}
+	}
+	clientSide := "No"
+	if pkg.IsClientSide() {
+		clientSide = "Yes"
+	}
+
+	embed.Fields = []*discordgo.MessageEmbedField{
+		{Name: "Rating", Value: fmt.Sprintf("%d", pkg.RatingScore), Inline: true},
+		{Name: "Downloads", Value: fmt.Sprintf("%d", pkg.Downloads()), Inline: true},
+		{Name: "Latest version", Value: latest, Inline: true},
+		{Name: "Required by client", Value: clientSide, Inline: true},
+	}
+	if len(pkg.Categories) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Categories", Value: truncateField(strings.Join(pkg.Categories, ", "))})
+	}
+	if pkg.IsDeprecated {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Deprecated", Value: "This package is deprecated, and can't be installed"})
+	}
+	return embed
+}
+
+func (h *handler) handleSearchMods(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	sendInteractionDeferred(session, interaction)
+	ctx := context.Background()
+
+	query, _ := optionValue(interaction.ApplicationCommandData().Options, SearchModsQuery)
+	packages, err := thunderstore.SearchPackages(ctx, query.StringValue(), maxSearchResults)
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unable to search thunderstore: %v", err), nil)
+
+		return
+	}
+	if len(packages) == 0 {
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("No mods found for: %s", query.StringValue()), nil)
+
+		return
+	}
+
+	embeds := make([]*discordgo.MessageEmbed, 0, len(packages))
+	buttons := make([]discordgo.MessageComponent, 0, len(packages))
+	for _, pkg := range packages {
+		embeds = append(embeds, packageEmbed(pkg))
+		customID := modComponentID(ModActionAddToPreset, pkg.Owner+"/"+pkg.Name)
+		if pkg.IsDeprecated || len(customID) > maxCustomIDLength {
+			continue
+		}
+		buttons = append(buttons, discordgo.Button{
+			Label:    truncateLabel("Add " + pkg.Name),
+			Style:    discordgo.PrimaryButton,
+			CustomID: customID,
+		})
+	}
+
+	var components []discordgo.MessageComponent
+	if len(buttons) > 0 {
+		components = []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+	}
+	editDeferredInteractionEmbeds(session, interaction.Interaction, fmt.Sprintf("Add a mod to a preset, then create the server with /%s %s:<preset>", CreateServer, CreateServerPreset), embeds, components, nil)
+}
+
+// truncateLabel shortens the label to the maximum length discord accepts for buttons
+func truncateLabel(label string) string {
+	const maxLabelLength = 80
+	if len(label) <= maxLabelLength {
+		return label
+	}
+	return label[:maxLabelLength-3] + "..."
+}
+
+func (h *handler) handleModComponent(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	action, packageName, err := parseModComponentID(interaction.MessageComponentData().CustomID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if action != ModActionAddToPreset {
+		log.Println(fmt.Sprintf("unknown mod component action: %s", action))
+		return
+	}
+
+	if err := session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: modComponentID(ModActionAddToPreset, packageName),
+			Title:    "Add mod to preset",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    presetNameInput,
+							Label:       "Personal preset. Created when it doesn't exist",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "my_mods",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+	}); err != nil {
+		log.Println("Error sending modal: ", err)
+	}
+}
+
+func (h *handler) handleModModal(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	ctx := context.Background()
+	data := interaction.ModalSubmitData()
+	action, packageName, err := parseModComponentID(data.CustomID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	sendInteractionDeferredEphemeral(session, interaction)
+
+	if action != ModActionAddToPreset {
+		editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("unknown action: %s", action), nil)
+
+		return
+	}
+
+	presetName := strings.TrimSpace(modalValue(data, presetNameInput))
+	if presetName == "" {
+		editDeferredInteractionReply(session, interaction.Interaction, "preset name must not be empty", nil)
+
+		return
+	}
+
+	p, err := h.addModToPreset(ctx, interaction, presetName, packageName)
+	if err != nil {
+		editDeferredInteractionReply(session, interaction.Interaction, err.Error(), nil)
+
+		return
+	}
+
+	editDeferredInteractionReply(session, interaction.Interaction, fmt.Sprintf("added %s to your preset **%s**: %s\nCreate the server with /%s %s:%s", packageName, p.Name, formatPresetOptions(p.Options), CreateServer, CreateServerPreset, p.Name), nil)
+}
+
+// addModToPreset adds the thunderstore package to custom mods of the personal preset. Missing preset is created,
+// starting from the discord server wide preset with the same name, if there is one.
+func (h *handler) addModToPreset(ctx context.Context, interaction *discordgo.InteractionCreate, presetName string, packageName string) (*preset.Preset, error) {
+	userID := interaction.Member.User.ID
+	presets, err := h.presetRepo.List(ctx, interaction.GuildID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list presets: %w", err)
+	}
+
+	options := make(map[string]interface{})
+	for _, p := range presets {
+		if p.Name != presetName {
+			continue
+		}
+		if p.Scope() == preset.ScopeUser || len(options) == 0 {
+			options = make(map[string]interface{}, len(p.Options))
+			for k, v := range p.Options {
+				options[k] = v
+			}
+		}
+	}
+
+	customMods, _ := options[CreateServerCustomThunderstoreMods].(string)
+	var mods []string
+	for _, m := range strings.Split(customMods, ",") {
+		if m = strings.TrimSpace(m); m == "" {
+			continue
+		}
+		name, _ := thunderstore.SplitVersion(m)
+		if name == packageName {
+			return nil, fmt.Errorf("%s is already in the preset %s", packageName, presetName)
+		}
+		mods = append(mods, m)
+	}
+	options[CreateServerCustomThunderstoreMods] = strings.Join(append(mods, packageName), ",")
+
+	p := &preset.Preset{
+		Name:      presetName,
+		GuildID:   interaction.GuildID,
+		UserID:    userID,
+		CreatedBy: userID,
+		Options:   options,
+	}
+	err = h.presetRepo.Store(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("unable to save preset: %w", err)
+	}
+	return p, nil
+}
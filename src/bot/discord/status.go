@@ -0,0 +1,285 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/l1ghthouse/northstar-bootstrap/src/masterserver"
+	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+	"github.com/l1ghthouse/northstar-bootstrap/src/providers"
+	"github.com/l1ghthouse/northstar-bootstrap/src/providers/util"
+)
+
+// statusBoardMarker is the footer of the status message, and is used to find the message after restart
+const statusBoardMarker = "northstar-bot status board"
+
+// maxEmbedFields is the maximum number of fields discord accepts in a single embed
+const maxEmbedFields = 25
+
+// maxEmbedLength is the maximum number of characters discord accepts in titles, descriptions, fields, and footer of
+// an embed
+const maxEmbedLength = 6000
+
+const maxStatusModsLength = 200
+
+// statusBoard keeps pinned message in the status channel of every guild, listing the running servers
+type statusBoard struct {
+	session *discordgo.Session
+	h       *handler
+	// channels maps guild id to the status channel
+	channels map[string]string
+	lock     *sync.Mutex
+	// messages maps guild id to the status message
+	messages map[string]string
+	refreshC chan struct{}
+}
+
+func newStatusBoard(session *discordgo.Session, h *handler, channels map[string]string) *statusBoard {
+	return &statusBoard{
+		session:  session,
+		h:        h,
+		channels: channels,
+		lock:     &sync.Mutex{},
+		messages: make(map[string]string),
+		refreshC: make(chan struct{}, 1),
+	}
+}
+
+// requestRefresh schedules refresh of the status messages, without waiting for it
+func (b *statusBoard) requestRefresh() {
+	if b == nil || len(b.channels) == 0 {
+		return
+	}
+	select {
+	case b.refreshC <- struct{}{}:
+	default:
+	}
+}
+
+func (b *statusBoard) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.refreshC:
+			b.refresh(ctx)
+		}
+	}
+}
+
+func (b *statusBoard) refresh(ctx context.Context) {
+	if b == nil || len(b.channels) == 0 {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	servers, err := b.h.p.GetRunningServers(ctx)
+	if err != nil {
+		log.Println(fmt.Sprintf("status board: unable to list running servers: %v", err))
+		return
+	}
+	cachedServers, err := b.h.nsRepo.GetAll(ctx)
+	if err != nil {
+		log.Println(fmt.Sprintf("status board: unable to list cached servers: %v", err))
+		return
+	}
+	mergeCachedServers(servers, cachedServers)
+	players := playerCounts(ctx, servers)
+
+	// the warm pool is shared by every guild, so its cost is only shown to the default guild, that manages the bot
+	var pool []util.PoolStatus
+	if warmPool, ok := b.h.p.(providers.WarmPool); ok && b.channels[b.h.defaultGuildID] != "" {
+		pool, err = warmPool.WarmPoolStatus(ctx)
+		if err != nil {
+			log.Println(fmt.Sprintf("status board: unable to get warm pool status: %v", err))
+		}
+	}
+
+	for guildID, channelID := range b.channels {
+		if channelID == "" {
+			continue
+		}
+		embed := b.h.statusEmbed(b.h.guildServers(guildID, servers, cachedServers), players)
+		if field := poolField(pool); field != nil && guildID == b.h.defaultGuildID && len(embed.Fields) < maxEmbedFields {
+			embed.Fields = append(embed.Fields, field)
+		}
+		if err := b.publish(guildID, channelID, embed); err != nil {
+			log.Println(fmt.Sprintf("status board: discord server %s: %v", guildID, err))
+		}
+	}
+}
+
+// publish edits the status message of the guild, or sends, and pins a new one
+func (b *statusBoard) publish(guildID, channelID string, embed *discordgo.MessageEmbed) error {
+	messageID, ok := b.messages[guildID]
+	if !ok {
+		messageID = b.findMessage(channelID)
+	}
+	if messageID != "" {
+		_, err := b.session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:      messageID,
+			Channel: channelID,
+			Embeds:  []*discordgo.MessageEmbed{embed},
+		})
+		if err == nil {
+			b.messages[guildID] = messageID
+			return nil
+		}
+		log.Println(fmt.Sprintf("status board: unable to edit status message, sending a new one: %v", err))
+	}
+
+	message, err := b.session.ChannelMessageSendEmbed(channelID, embed)
+	if err != nil {
+		return fmt.Errorf("unable to send status message: %w", err)
+	}
+	b.messages[guildID] = message.ID
+	if err := b.session.ChannelMessagePin(channelID, message.ID); err != nil {
+		log.Println(fmt.Sprintf("status board: unable to pin status message: %v", err))
+	}
+	return nil
+}
+
+// findMessage looks for the status message among pinned messages of the channel
+func (b *statusBoard) findMessage(channelID string) string {
+	pinned, err := b.session.ChannelMessagesPinned(channelID)
+	if err != nil {
+		log.Println(fmt.Sprintf("status board: unable to get pinned messages: %v", err))
+		return ""
+	}
+	for _, message := range pinned {
+		if message.Author == nil || message.Author.ID != b.session.State.User.ID {
+			continue
+		}
+		for _, embed := range message.Embeds {
+			if embed.Footer != nil && embed.Footer.Text == statusBoardMarker {
+				return message.ID
+			}
+		}
+	}
+	return ""
+}
+
+// playerCounts returns player count by server name, for servers registered with their master server
+func playerCounts(ctx context.Context, servers []*nsserver.NSServer) map[string]string {
+	listed := make(map[string][]masterserver.Server)
+	for _, server := range servers {
+		masterServer := server.MasterServer
+		if masterServer == "" {
+			masterServer = DefaultMasterServer
+		}
+		if _, ok := listed[masterServer]; ok {
+			continue
+		}
+		masterServers, err := masterserver.GetServers(ctx, masterServer)
+		if err != nil {
+			log.Println(fmt.Sprintf("status board: unable to list servers from %s: %v", masterServer, err))
+		}
+		listed[masterServer] = masterServers
+	}
+
+	counts := make(map[string]string)
+	for _, server := range servers {
+		masterServer := server.MasterServer
+		if masterServer == "" {
+			masterServer = DefaultMasterServer
+		}
+		registeredName := server.ListedName()
+		for _, s := range listed[masterServer] {
+			if s.Name == registeredName || s.Name == server.Name {
+				counts[server.Name] = fmt.Sprintf("%d/%d", s.PlayerCount, s.MaxPlayers)
+				break
+			}
+		}
+	}
+	return counts
+}
+
+// poolField lists idle instances of the warm pool, and what they cost
+func poolField(pool []util.PoolStatus) *discordgo.MessageEmbedField {
+	if len(pool) == 0 {
+		return nil
+	}
+	builder := strings.Builder{}
+	var total float64
+	for _, s := range pool {
+		builder.WriteString(fmt.Sprintf("%s: %d/%d idle, $%.3f/hour", s.Region, s.Idle, s.Size, s.HourlyCost))
+		builder.WriteString("\n")
+		total += s.HourlyCost
+	}
+	builder.WriteString(fmt.Sprintf("Total idle cost: $%.3f/hour", total))
+	return &discordgo.MessageEmbedField{Name: "Warm pool", Value: truncateField(builder.String())}
+}
+
+func (h *handler) statusEmbed(servers []*nsserver.NSServer, players map[string]string) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:     "Running servers",
+		Footer:    &discordgo.MessageEmbedFooter{Text: statusBoardMarker},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	if len(servers) == 0 {
+		embed.Description = "No servers running"
+		return embed
+	}
+
+	more := func(idx int) string {
+		return fmt.Sprintf("%d more servers are running. Use /%s to see all of them", len(servers)-idx, ListServer)
+	}
+	// the description listing the rest is reserved, so it always fits
+	length := len(embed.Title) + len(embed.Footer.Text) + len(more(0))
+	for idx, server := range servers {
+		if idx == maxEmbedFields-1 && len(servers) > maxEmbedFields {
+			embed.Description = more(idx)
+			break
+		}
+
+		user := unknown
+		if server.RequestedBy != "" {
+			user = fmt.Sprintf("<@%s>", server.RequestedBy)
+		}
+		playerCount, ok := players[server.Name]
+		if !ok {
+			playerCount = "not listed"
+		}
+
+		builder := strings.Builder{}
+		builder.WriteString(fmt.Sprintf("Region: %s, Password: %s", server.Region, serverPassword(server)))
+		builder.WriteString("\n")
+		builder.WriteString(fmt.Sprintf("Requested by: %s", user))
+		builder.WriteString("\n")
+		builder.WriteString(fmt.Sprintf("Players: %s", playerCount))
+		builder.WriteString("\n")
+		if deleteAt, ok := h.deleteAt(server); ok {
+			builder.WriteString(fmt.Sprintf("Deleted <t:%d:R>", deleteAt.Unix()))
+			builder.WriteString("\n")
+		}
+		if mods := installedMods(server); len(mods) > 0 {
+			names := make([]string, len(mods))
+			for idx, m := range mods {
+				names[idx] = m.Name
+			}
+			modList := strings.Join(names, ", ")
+			if len(modList) > maxStatusModsLength {
+				modList = modList[:maxStatusModsLength-3] + "..."
+			}
+			builder.WriteString(fmt.Sprintf("Mods: %s", modList))
+		}
+
+		field := &discordgo.MessageEmbedField{
+			Name:  server.Name,
+			Value: truncateField(builder.String()),
+		}
+		length += len(field.Name) + len(field.Value)
+		if length > maxEmbedLength {
+			embed.Description = more(idx)
+			break
+		}
+		embed.Fields = append(embed.Fields, field)
+	}
+	return embed
+}
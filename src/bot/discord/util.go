@@ -46,3 +46,22 @@ func sendInteractionDeferred(s *discordgo.Session, i *discordgo.InteractionCreat
 		log.Println("Error sending message: ", err)
 	}
 }
+
+func editDeferredInteractionEmbeds(session *discordgo.Session, interaction *discordgo.Interaction, msg string, embeds []*discordgo.MessageEmbed, components []discordgo.MessageComponent, files []*discordgo.File) {
+	response := &discordgo.WebhookEdit{Content: msg, Embeds: embeds, Components: components, Files: files}
+	_, err := session.InteractionResponseEdit(interaction, response)
+	if err != nil {
+		log.Println(fmt.Sprintf("failed to update interaction. error: %v", err))
+	}
+}
+
+func sendInteractionDeferredEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: uint64(discordgo.MessageFlagsEphemeral),
+		},
+	}); err != nil {
+		log.Println("Error sending message: ", err)
+	}
+}
@@ -2,7 +2,10 @@ package config
 
 import (
 	"github.com/l1ghthouse/northstar-bootstrap/src/bot"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod/thunderstore"
 	"github.com/l1ghthouse/northstar-bootstrap/src/providers"
+	"github.com/l1ghthouse/northstar-bootstrap/src/providers/util"
 	"github.com/l1ghthouse/northstar-bootstrap/src/storage"
 )
 
@@ -14,6 +17,11 @@ type Config struct {
 	MaxServerExtendDurationSeconds uint `default:"0"`
 	MaxServersPerHour              int  `default:"-1"`
 	MaxLifetimeSeconds             uint `default:"6900"` // 2 hours - 5 minutes, since vultr charges hourly
+	// Mods are merged with the built-in mods, and replace the built-in ones with the same name
+	Mods         []mod.Definition
+	Thunderstore thunderstore.Config
+	// Versions are discovered from tags of the northstar dedicated image
+	Versions util.VersionsConfig
 }
 
 // MaxLifetimeSeconds could be optimized per cloud provider, depending on the billing cycle.
@@ -0,0 +1,33 @@
+package image
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// Image is a provider snapshot of a bootstrapped host. Servers of the region boot from it, instead of downloading
+// the game files
+type Image struct {
+	ID         uuid.UUID `json:"id,omitempty" gorm:"type:uuid;primary_key;"`
+	Region     string    `json:"region" gorm:"not null;default:null;uniqueIndex"`
+	SnapshotID string    `json:"snapshotID" gorm:"not null;default:null"`
+	// ServerFiles are the game files baked into the snapshot. Snapshots of other files are not used
+	ServerFiles string `json:"serverFiles" gorm:"not null;default:null"`
+	BuiltBy     string `json:"builtBy" gorm:"default:null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (i *Image) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == uuid.Nil {
+		u, err := uuid.NewV4()
+		if err != nil {
+			return fmt.Errorf("failed to create uuid: %w", err)
+		}
+		i.ID = u
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package image
+
+import (
+	"context"
+)
+
+type Repo interface {
+	GetAll(ctx context.Context) ([]*Image, error)
+	// Store creates the image, or replaces the snapshot of an existing image for the same region
+	Store(ctx context.Context, i *Image) error
+}
@@ -0,0 +1,86 @@
+package mod
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checksumTTL limits how long checksum of an archive is reused. Versioned archives don't change, but archives
+// served from plain urls could.
+const checksumTTL = time.Hour
+
+type checksum struct {
+	sum        string
+	computedAt time.Time
+}
+
+var checksums = struct {
+	lock *sync.Mutex
+	sums map[string]checksum
+}{
+	lock: &sync.Mutex{},
+	sums: make(map[string]checksum),
+}
+
+// archiveSHA256 downloads the archive once, and returns its hex encoded SHA-256
+func archiveSHA256(ctx context.Context, link string) (string, error) {
+	checksums.lock.Lock()
+	cached, ok := checksums.sums[link]
+	checksums.lock.Unlock()
+	if ok && time.Since(cached.computedAt) < checksumTTL {
+		return cached.sum, nil
+	}
+
+	client := http.Client{
+		Timeout: time.Minute * 5,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed http call to download %s: %w", link, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d while downloading %s", res.StatusCode, link)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, res.Body); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", link, err)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	checksums.lock.Lock()
+	checksums.sums[link] = checksum{sum: sum, computedAt: time.Now()}
+	checksums.lock.Unlock()
+	return sum, nil
+}
+
+// cmdVerifiedWgetZipBuilder downloads the zip, and aborts the startup script when its checksum doesn't match the
+// one computed by the bot
+func cmdVerifiedWgetZipBuilder(ctx context.Context, link string, zipName string) (string, string, error) {
+	sum, err := archiveSHA256(ctx, link)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to compute checksum of %s: %w", zipName, err)
+	}
+	archive := workPath(ctx, zipName+".zip")
+	builder := strings.Builder{}
+	builder.WriteString(cmdWgetZipBuilder(link, archive))
+	builder.WriteString(fmt.Sprintf("if ! echo \"%s  %s\" | sha256sum -c -; then echo \"checksum mismatch for %s.zip, downloaded from %s\" >&2; exit 1; fi", sum, archive, zipName, link))
+	builder.WriteString("\n")
+	return builder.String(), sum, nil
+}
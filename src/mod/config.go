@@ -0,0 +1,209 @@
+package mod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"al.essio.dev/pkg/shellescape"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod/thunderstore"
+)
+
+// declaredConVars maps mod name to ConVars the mod is known to have. ConVars of mods without declarations are not
+// validated.
+var declaredConVars = map[string][]string{}
+
+var ErrInvalidConVar = errors.New("invalid mod convar")
+
+// modsDirInstaller is implemented by mods, that may have no folders in the mods directory, like mods that only
+// replace the mods shipped with the server. Other mods are assumed to install folders to the mods directory
+type modsDirInstaller interface {
+	installsToModsDir() bool
+}
+
+func installsToModsDir(m Mod) bool {
+	installer, ok := m.(modsDirInstaller)
+	return !ok || installer.installsToModsDir()
+}
+
+// modByName returns the known mod, or the custom mod of the option
+func modByName(name string) (Mod, error) {
+	if generator, ok := ByName[name]; ok {
+		return generator(), nil
+	}
+	return CustomMod(name)
+}
+
+var conVarNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseConVars parses comma separated list of mod.ConVar=value pairs. ConVars are returned keyed by mod.ConVar
+func ParseConVars(option string) (map[string]string, error) {
+	conVars := make(map[string]string)
+	for _, pair := range strings.Split(option, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		idx := strings.Index(pair, "=")
+		if idx == -1 {
+			return nil, fmt.Errorf("%w: %s. Must be formatted as mod.ConVar=value", ErrInvalidConVar, pair)
+		}
+		key, value := strings.TrimSpace(pair[:idx]), strings.TrimSpace(pair[idx+1:])
+		modName, conVar, err := SplitConVarKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("%w: value of %s must be a single line", ErrInvalidConVar, key)
+		}
+		conVars[modName+"."+conVar] = value
+	}
+	return conVars, nil
+}
+
+// SplitConVarKey splits mod.ConVar into the mod, and the ConVar name
+func SplitConVarKey(key string) (string, string, error) {
+	idx := strings.LastIndex(key, ".")
+	if idx <= 0 || !conVarNameRegexp.MatchString(key[idx+1:]) {
+		return "", "", fmt.Errorf("%w: %s. Must be formatted as mod.ConVar=value", ErrInvalidConVar, key)
+	}
+	return key[:idx], key[idx+1:], nil
+}
+
+// ValidateConVars checks that ConVars belong to the enabled mods, that have folders in the mods directory, and are
+// declared by them, when declarations of the mod are known
+func ValidateConVars(conVars map[string]string, enabled map[string]bool) error {
+	var violations RuleErrors
+	keys := make([]string, 0, len(conVars))
+	for key := range conVars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		modName, conVar, err := SplitConVarKey(key)
+		if err != nil {
+			violations = append(violations, err)
+			continue
+		}
+		if !enabled[modName] {
+			violations = append(violations, fmt.Errorf("%w: %s is set, but %s is not enabled", ErrInvalidConVar, key, modName))
+			continue
+		}
+		m, err := modByName(modName)
+		if err != nil {
+			violations = append(violations, err)
+			continue
+		}
+		if !installsToModsDir(m) {
+			violations = append(violations, fmt.Errorf("%w: %s replaces mods shipped with the server, so its convars can't be set", ErrInvalidConVar, modName))
+			continue
+		}
+		declared, ok := declaredConVars[modName]
+		if !ok {
+			continue
+		}
+		known := false
+		for _, name := range declared {
+			if name == conVar {
+				known = true
+				break
+			}
+		}
+		if !known {
+			violations = append(violations, fmt.Errorf("%w: %s doesn't have %s. Known convars: %s", ErrInvalidConVar, modName, conVar, strings.Join(declared, ", ")))
+		}
+	}
+	if len(violations) > 0 {
+		return violations
+	}
+	return nil
+}
+
+// ConVarsCmd sets default values of the ConVars in mod.json of the folders, that the mod installed to the mods
+// directory. modsDirs are the directories the folders are copied from, keyed by mod name
+func ConVarsCmd(conVars map[string]string, modsDirs map[string]string) string {
+	keys := make([]string, 0, len(conVars))
+	for key := range conVars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	builder := strings.Builder{}
+	for _, key := range keys {
+		modName, conVar, err := SplitConVarKey(key)
+		if err != nil {
+			continue
+		}
+		modsDir, ok := modsDirs[modName]
+		if !ok || modsDir == "" {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf(`for d in %s/*/; do f="/mods/$(basename "$d")/mod.json"; [ -f "$f" ] || continue; tmp=$(mktemp) && jq --arg name %s --arg value %s '(.ConVars[]? | select(.Name == $name) | .DefaultValue) |= $value' "$f" > "$tmp" && mv "$tmp" "$f"; done`,
+			shellescape.Quote(modsDir), shellescape.Quote(conVar), shellescape.Quote(conVars[key])))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+var ErrInvalidModFile = errors.New("invalid mod file")
+
+// validateFilePath checks that the file is written inside the mods directory
+func validateFilePath(file string) error {
+	cleaned := path.Clean(file)
+	if path.IsAbs(cleaned) || cleaned == "." || strings.HasPrefix(cleaned, "..") {
+		return fmt.Errorf("%w: %s must be relative to the mods directory", ErrInvalidModFile, file)
+	}
+	return nil
+}
+
+// FilesCmd writes files into the mods directory, replacing the files installed by the mods
+func FilesCmd(files map[string]string) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	builder := strings.Builder{}
+	for _, name := range names {
+		target := path.Join("/mods", path.Clean(name))
+		builder.WriteString(fmt.Sprintf("mkdir -p %s", shellescape.Quote(path.Dir(target))))
+		builder.WriteString("\n")
+		builder.WriteString(fmt.Sprintf("printf '%%s' %s > %s", shellescape.Quote(files[name]), shellescape.Quote(target)))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// withFiles is a mod, with configuration files written over its installed files
+type withFiles struct {
+	Mod
+	files map[string]string
+}
+
+func (w withFiles) installsToModsDir() bool {
+	return installsToModsDir(w.Mod)
+}
+
+func (w withFiles) thunderstoreRequest() (thunderstore.Request, bool) {
+	return thunderstoreRequest(w.Mod)
+}
+
+func (w withFiles) Spec(ctx context.Context) (ModSpec, error) {
+	spec, err := w.Mod.Spec(ctx)
+	if err != nil {
+		return spec, err
+	}
+	if spec.Files == nil {
+		spec.Files = make(map[string]string, len(w.files))
+	}
+	for name, content := range w.files {
+		spec.Files[name] = content
+	}
+	return spec, nil
+}
@@ -0,0 +1,34 @@
+package mod
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateConVarsOfOverrideMods(t *testing.T) {
+	enabled := map[string]bool{
+		"ctf_experimental":                     true,
+		"github.com/Example/NorthstarMods@fix": true,
+		"Example/Package":                      true,
+	}
+	for _, key := range []string{"ctf_experimental.ctf_rounds", "github.com/Example/NorthstarMods@fix.ctf_rounds"} {
+		err := ValidateConVars(map[string]string{key: "1"}, enabled)
+		if !errors.Is(err, ErrInvalidConVar) || !strings.Contains(err.Error(), "replaces mods shipped with the server") {
+			t.Errorf("%s: expected the convar to be rejected, got %v", key, err)
+		}
+	}
+	if err := ValidateConVars(map[string]string{"Example/Package.rounds": "1"}, enabled); err != nil {
+		t.Errorf("expected convar of a thunderstore mod to be accepted, got %v", err)
+	}
+}
+
+func TestConVarsCmdTargetsTheMod(t *testing.T) {
+	cmd := ConVarsCmd(map[string]string{"configured.rounds": "5", "mounted.rounds": "3"}, map[string]string{"configured": "/configured/mods", "mounted": ""})
+	if !strings.Contains(cmd, "for d in /configured/mods/*/;") {
+		t.Errorf("convar isn't set in folders of the mod\n%s", cmd)
+	}
+	if strings.Count(cmd, "\n") != 1 || strings.Contains(cmd, "/mods/*/mod.json") {
+		t.Errorf("convar is set outside folders of the mod\n%s", cmd)
+	}
+}
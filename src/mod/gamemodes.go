@@ -0,0 +1,140 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GameContent lists playlists, maps, and game modes the server can be started with
+type GameContent struct {
+	Playlists []string
+	Maps      []string
+	Modes     []string
+}
+
+func (c GameContent) isEmpty() bool {
+	return len(c.Playlists) == 0 && len(c.Maps) == 0 && len(c.Modes) == 0
+}
+
+// baseGameContent is available on every server
+var baseGameContent = GameContent{
+	Playlists: []string{
+		"private_match", "aitdm", "at", "coliseum", "cp", "ctf", "ffa", "fra", "lts", "mfd", "ps", "speedball",
+		"tdm", "ttdm", "chamber", "ctf_comp", "fastball", "gg", "hidden", "hs", "inf", "kr", "sns", "tffa", "tt",
+	},
+	Maps: []string{
+		"mp_angel_city", "mp_black_water_canal", "mp_colony02", "mp_complex3", "mp_crashsite3", "mp_drydock",
+		"mp_eden", "mp_forwardbase_kodai", "mp_glitch", "mp_grave", "mp_homestead", "mp_relic02", "mp_rise",
+		"mp_thaw", "mp_wargames", "mp_lf_deck", "mp_lf_meadow", "mp_lf_stacks", "mp_lf_township", "mp_lf_traffic",
+		"mp_lf_uma", "mp_coliseum", "mp_coliseum_column",
+	},
+	Modes: []string{
+		"aitdm", "at", "coliseum", "cp", "ctf", "ffa", "fra", "lts", "mfd", "ps", "speedball", "tdm", "ttdm",
+		"chamber", "ctf_comp", "fastball", "gg", "hidden", "hs", "inf", "kr", "sns", "tffa", "tt",
+	},
+}
+
+// modGameContent maps mod name to the content, that becomes available when the mod is enabled
+var modGameContent = map[string]GameContent{
+	"ctf_experimental": {
+		Playlists: []string{"fd_easy", "fd_normal", "fd_hard", "fd_master", "fd_insane"},
+		Modes:     []string{"fd"},
+	},
+}
+
+// AvailableGameContent returns sorted content available with the given mods enabled
+func AvailableGameContent(enabled map[string]bool) GameContent {
+	playlists := append([]string(nil), baseGameContent.Playlists...)
+	maps := append([]string(nil), baseGameContent.Maps...)
+	modes := append([]string(nil), baseGameContent.Modes...)
+	for name, content := range modGameContent {
+		if enabled != nil && !enabled[name] {
+			continue
+		}
+		playlists = append(playlists, content.Playlists...)
+		maps = append(maps, content.Maps...)
+		modes = append(modes, content.Modes...)
+	}
+	return GameContent{
+		Playlists: uniqueSorted(playlists),
+		Maps:      uniqueSorted(maps),
+		Modes:     uniqueSorted(modes),
+	}
+}
+
+// KnownGameContent returns content available with any of the mods
+func KnownGameContent() GameContent {
+	return AvailableGameContent(nil)
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// ModsProviding returns sorted names of mods, that make the value available
+func ModsProviding(value string) []string {
+	var names []string
+	for name, content := range modGameContent {
+		for _, values := range [][]string{content.Playlists, content.Maps, content.Modes} {
+			if contains(values, value) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrUnknownGameOption = errors.New("unknown game options")
+
+// gameOptionProblem describes why the value isn't available, or returns empty string when it is
+func gameOptionProblem(kind, value string, available []string) string {
+	if value == "" || contains(available, value) {
+		return ""
+	}
+	if providers := ModsProviding(value); len(providers) > 0 {
+		return fmt.Sprintf("%s %s requires one of the mods: %s", kind, value, strings.Join(providers, ", "))
+	}
+	return fmt.Sprintf("%s %s is not known", kind, value)
+}
+
+// ValidateGameOptions checks that playlist, map, and mode are known, and provided by the enabled mods. Empty values
+// are not validated
+func ValidateGameOptions(playlist, mapName, mode string, enabled map[string]bool) error {
+	available := AvailableGameContent(enabled)
+	var problems []string
+	for _, problem := range []string{
+		gameOptionProblem("playlist", playlist, available.Playlists),
+		gameOptionProblem("map", mapName, available.Maps),
+		gameOptionProblem("mode", mode, available.Modes),
+	} {
+		if problem != "" {
+			problems = append(problems, "- "+problem)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w:\n%s", ErrUnknownGameOption, strings.Join(problems, "\n"))
+	}
+	return nil
+}
@@ -3,11 +3,12 @@ package mod
 import (
 	"context"
 	"fmt"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod/thunderstore"
 )
 
 type Mod interface {
-	ModParams(ctx context.Context) (cmd string, dockerArgs string, downloadLink string, version string, requiredByClient bool, err error)
-	Validate(otherMods []Mod) error
+	Spec(ctx context.Context) (ModSpec, error)
 	EnabledByDefault() bool
 }
 
@@ -63,18 +64,36 @@ var ByName = map[string]func() Mod{
 type ThunderstoreMod struct {
 	Enabled bool
 	Name    string
+	// Version pins the package version. Latest version is installed when empty
+	Version string
 }
 
-func (r ThunderstoreMod) Validate(otherMods []Mod) error {
-	return nil
-}
-
-func (h ThunderstoreMod) ModParams(ctx context.Context) (string, string, string, string, bool, error) {
-	return latestThunderstoreMod(ctx, h.Name)
+func (h ThunderstoreMod) Spec(ctx context.Context) (ModSpec, error) {
+	return thunderstoreMod(ctx, h.Name, h.Version)
 }
 
 func (h ThunderstoreMod) EnabledByDefault() bool {
 	return h.Enabled
 }
 
+func (h ThunderstoreMod) thunderstoreRequest() (thunderstore.Request, bool) {
+	return thunderstore.Request{Name: h.Name, Version: h.Version}, true
+}
+
+func (h ThunderstoreMod) withVersion(version string) Mod {
+	h.Version = version
+	return &h
+}
+
+// CustomThunderstoreMod returns mod for a thunderstore package passed as Owner/Name, optionally pinned to a version
+// with Owner/Name@1.2.3
+func CustomThunderstoreMod(option string) Mod {
+	name, version := thunderstore.SplitVersion(option)
+	return ThunderstoreMod{
+		Enabled: false,
+		Name:    name,
+		Version: version,
+	}
+}
+
 var ErrNoTagsFound = fmt.Errorf("no tags found")
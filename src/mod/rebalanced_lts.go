@@ -2,42 +2,34 @@ package mod
 
 import (
 	"context"
-	"fmt"
-	"strings"
 )
 
 type RebalancedLTS struct {
 	PreRelease bool
+	// Tag pins the release. Latest release is installed when empty
+	Tag string
 }
 
 const LTSRebalancedRepoOwner = "Dinorush"
 const LTSRebalancedRepoName = "LTSRebalance"
 const LTSRebalancedModName = LTSRebalancedRepoOwner + "." + LTSRebalancedRepoName
 
-func (r RebalancedLTS) ModParams(ctx context.Context) (string, string, string, string, bool, error) {
-	latestTag, err := latestGithubReleaseTag(ctx, LTSRebalancedRepoOwner, LTSRebalancedRepoName, r.PreRelease)
-	if err != nil {
-		return "", "", "", "", false, err
-	}
-	link := fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s_v%s_pugs_ver.zip", LTSRebalancedRepoOwner, LTSRebalancedRepoName, latestTag, LTSRebalancedModName, latestTag)
-	builder := strings.Builder{}
-	builder.WriteString(cmdWgetZipBuilder(link, LTSRebalancedModName))
-	builder.WriteString(cmdUnzipBuilderWithDst(LTSRebalancedModName))
-	builder.WriteString(fmt.Sprintf("cp -r /%s/mods/* /mods/", LTSRebalancedModName))
-	builder.WriteString("\n")
-	return builder.String(), "", link, latestTag, true, nil
-}
-
-func (r RebalancedLTS) Validate(otherMods []Mod) error {
-	for _, mod := range otherMods {
-		v, ok := mod.(*RebalancedLTS)
-		if ok && v.PreRelease != r.PreRelease {
-			return fmt.Errorf("cannot have both pre-release and release versions of Rebalanced LTS enabled. Please explicitly enable/disable both mods")
-		}
-	}
-	return nil
+func (r RebalancedLTS) Spec(ctx context.Context) (ModSpec, error) {
+	return GithubReleaseMod{
+		Owner:            LTSRebalancedRepoOwner,
+		Repo:             LTSRebalancedRepoName,
+		Tag:              r.Tag,
+		PreRelease:       r.PreRelease,
+		Asset:            LTSRebalancedModName + "_v{tag}_pugs_ver.zip",
+		RequiredByClient: true,
+	}.Spec(ctx)
 }
 
 func (r RebalancedLTS) EnabledByDefault() bool {
 	return false
 }
+
+func (r RebalancedLTS) withVersion(version string) Mod {
+	r.Tag = version
+	return &r
+}
@@ -0,0 +1,261 @@
+package mod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod/thunderstore"
+)
+
+const (
+	SourceThunderstore  = "thunderstore"
+	SourceGithubRelease = "github_release"
+	SourceGitBranch     = "git_branch"
+	SourceURL           = "url"
+)
+
+// versionPinner is implemented by mods, that can be pinned to a version
+type versionPinner interface {
+	withVersion(version string) Mod
+}
+
+// Definition describes a mod in the configuration file
+type Definition struct {
+	// Name is the mod name, used as create_server option
+	Name string `required:"true"`
+	// Source is one of: thunderstore, github_release, git_branch, url. Empty source pins version of the built-in
+	// mod with the same name
+	Source string
+	// Owner is the thunderstore package owner, or the github repository owner
+	Owner string
+	// Package is the thunderstore package name, or the github repository name
+	Package string
+	// Version pins thunderstore package version, or github release tag
+	Version string
+	// URL is the git repository for git_branch source, or the zip archive for url source
+	URL    string
+	Branch string
+	// Commit pins git_branch source to a commit
+	Commit string
+	// PreRelease selects the latest github pre-release, when version is empty
+	PreRelease bool
+	// Asset is a glob matched against github release asset names
+	Asset string
+	// ModsDir is the directory in the archive, or repository that contains mod folders
+	ModsDir string
+	// OverrideMods lists mod folders, that replace the mods shipped with the server
+	OverrideMods     []string
+	EnabledByDefault bool
+	// ConflictsWith lists mods that can't be enabled together with this one
+	ConflictsWith []string
+	// Requires lists mods that have to be enabled for this one to work
+	Requires []string
+	// Implies lists mods that are enabled along with this one
+	Implies []string
+	// RequiredByClient overrides detection of whether clients need the mod to join
+	RequiredByClient *bool
+	// ConVars declares ConVars of the mod, that can be set with mod_config
+	ConVars []string
+	// Files are written to the mods directory after installation, replacing .cfg, or mod.json files of the mod.
+	// Keys are paths relative to the mods directory
+	Files map[string]string
+	// Playlists, Maps, and Modes added by the mod, that can be selected on create_server when the mod is enabled
+	Playlists []string
+	Maps      []string
+	Modes     []string
+}
+
+var ErrInvalidDefinition = errors.New("invalid mod definition")
+
+// discord only accepts lowercase option names
+var modNameRegexp = regexp.MustCompile(`^[-_a-z0-9]{1,32}$`)
+
+// requiredByClient is a mod, with overridden requiredByClient
+type requiredByClient struct {
+	Mod
+	required bool
+}
+
+func (r requiredByClient) Spec(ctx context.Context) (ModSpec, error) {
+	spec, err := r.Mod.Spec(ctx)
+	spec.RequiredByClient = r.required
+	return spec, err
+}
+
+func (r requiredByClient) installsToModsDir() bool {
+	return installsToModsDir(r.Mod)
+}
+
+func (r requiredByClient) thunderstoreRequest() (thunderstore.Request, bool) {
+	return thunderstoreRequest(r.Mod)
+}
+
+func (d Definition) generator() (func() Mod, error) {
+	var generator func() Mod
+	switch d.Source {
+	case "":
+		builtin, ok := ByName[d.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: source is required for mods that are not built-in", ErrInvalidDefinition, d.Name)
+		}
+		if d.Version == "" {
+			return nil, fmt.Errorf("%w: %s: version is required to pin a built-in mod", ErrInvalidDefinition, d.Name)
+		}
+		if _, ok := builtin().(versionPinner); !ok {
+			return nil, fmt.Errorf("%w: %s: built-in mod can't be pinned to a version", ErrInvalidDefinition, d.Name)
+		}
+		generator = func() Mod {
+			return builtin().(versionPinner).withVersion(d.Version)
+		}
+	case SourceThunderstore:
+		if d.Package == "" {
+			return nil, fmt.Errorf("%w: %s: package is required for thunderstore mods", ErrInvalidDefinition, d.Name)
+		}
+		name := d.Package
+		if d.Owner != "" {
+			name = d.Owner + "/" + d.Package
+		}
+		generator = func() Mod {
+			return &ThunderstoreMod{Enabled: d.EnabledByDefault, Name: name, Version: d.Version}
+		}
+	case SourceGithubRelease:
+		if d.Owner == "" || d.Package == "" {
+			return nil, fmt.Errorf("%w: %s: owner, and package are required for github releases", ErrInvalidDefinition, d.Name)
+		}
+		generator = func() Mod {
+			return &GithubReleaseMod{
+				Owner:            d.Owner,
+				Repo:             d.Package,
+				Tag:              d.Version,
+				PreRelease:       d.PreRelease,
+				Asset:            d.Asset,
+				ModsDir:          d.ModsDir,
+				OverrideMods:     d.OverrideMods,
+				RequiredByClient: d.RequiredByClient != nil && *d.RequiredByClient,
+				Enabled:          d.EnabledByDefault,
+			}
+		}
+	case SourceGitBranch:
+		if d.URL == "" || (d.Branch == "" && d.Commit == "") {
+			return nil, fmt.Errorf("%w: %s: url, and branch or commit are required for git branches", ErrInvalidDefinition, d.Name)
+		}
+		generator = func() Mod {
+			return &GitRefMod{
+				Dir:              d.Name,
+				URL:              d.URL,
+				Ref:              d.Branch,
+				Commit:           d.Commit,
+				ModsDir:          d.ModsDir,
+				OverrideMods:     d.OverrideMods,
+				RequiredByClient: d.RequiredByClient != nil && *d.RequiredByClient,
+				Enabled:          d.EnabledByDefault,
+			}
+		}
+	case SourceURL:
+		if d.URL == "" {
+			return nil, fmt.Errorf("%w: %s: url is required", ErrInvalidDefinition, d.Name)
+		}
+		generator = func() Mod {
+			return &URLMod{
+				Dir:              d.Name,
+				URL:              d.URL,
+				Version:          d.Version,
+				ModsDir:          d.ModsDir,
+				RequiredByClient: d.RequiredByClient != nil && *d.RequiredByClient,
+				Enabled:          d.EnabledByDefault,
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s: unknown source %s", ErrInvalidDefinition, d.Name, d.Source)
+	}
+
+	if d.Source == SourceThunderstore && d.RequiredByClient != nil {
+		detected := generator
+		generator = func() Mod {
+			return &requiredByClient{Mod: detected(), required: *d.RequiredByClient}
+		}
+	}
+	if len(d.Files) > 0 {
+		for name := range d.Files {
+			if err := validateFilePath(name); err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidDefinition, d.Name, err)
+			}
+		}
+		configured := generator
+		generator = func() Mod {
+			return &withFiles{Mod: configured(), files: d.Files}
+		}
+	}
+	return generator, nil
+}
+
+// Load merges mods defined in the configuration file with the built-in ones. Definitions replace built-in mods
+// with the same name.
+func Load(definitions []Definition) error {
+	generators := make(map[string]func() Mod, len(definitions))
+	for _, d := range definitions {
+		if !modNameRegexp.MatchString(d.Name) {
+			return fmt.Errorf("%w: %s: name must be 1-32 lowercase letters, digits, - or _", ErrInvalidDefinition, d.Name)
+		}
+		if _, ok := generators[d.Name]; ok {
+			return fmt.Errorf("%w: %s is defined more than once", ErrInvalidDefinition, d.Name)
+		}
+		generator, err := d.generator()
+		if err != nil {
+			return err
+		}
+		generators[d.Name] = generator
+	}
+
+	for name, generator := range generators {
+		ByName[name] = generator
+	}
+
+	for _, d := range definitions {
+		for _, conVar := range d.ConVars {
+			if !conVarNameRegexp.MatchString(conVar) {
+				return fmt.Errorf("%w: %s: invalid convar %s", ErrInvalidDefinition, d.Name, conVar)
+			}
+		}
+		if len(d.ConVars) > 0 {
+			declaredConVars[d.Name] = d.ConVars
+		}
+	}
+
+	for _, d := range definitions {
+		content := GameContent{Playlists: d.Playlists, Maps: d.Maps, Modes: d.Modes}
+		if !content.isEmpty() {
+			modGameContent[d.Name] = content
+		}
+	}
+
+	for _, d := range definitions {
+		r := Relations{ConflictsWith: d.ConflictsWith, Requires: d.Requires, Implies: d.Implies}
+		if r.isEmpty() {
+			continue
+		}
+		if err := validateRelations(d.Name, r); err != nil {
+			return err
+		}
+		declared := relations[d.Name]
+		relations[d.Name] = Relations{
+			ConflictsWith: append(declared.ConflictsWith, r.ConflictsWith...),
+			Requires:      append(declared.Requires, r.Requires...),
+			Implies:       append(declared.Implies, r.Implies...),
+		}
+	}
+	return nil
+}
+
+// Names returns sorted names of known mods
+func Names() []string {
+	names := make([]string, 0, len(ByName))
+	for name := range ByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -7,29 +7,36 @@ import (
 
 type RemoveNavmesh struct{}
 
-func (r RemoveNavmesh) ModParams(ctx context.Context) (string, string, string, string, bool, error) {
+func (r RemoveNavmesh) Spec(ctx context.Context) (ModSpec, error) {
 	fileContainerOrigin := "/usr/lib/northstar/R2Northstar/mods/"
 	folders := []string{
 		"Northstar.CustomServers/mod/maps/graphs",
 		"Northstar.CustomServers/mod/maps/navmesh",
 	}
 
-	emptyDir := "/empty_dir"
-	cmd := fmt.Sprintf("mkdir %s", emptyDir)
-	dockerArgs := ""
+	emptyDir := workPath(ctx, "empty_dir")
+	cmd := fmt.Sprintf("mkdir -p %s", emptyDir)
 
+	mounts := make([]Mount, 0, len(folders))
 	for _, path := range folders {
-		dockerArgs += fmt.Sprintf("--mount \"type=bind,source=%s,target=%s,readonly\"", emptyDir, fileContainerOrigin+path)
-		dockerArgs += " "
+		mounts = append(mounts, Mount{
+			Source:   emptyDir,
+			Target:   fileContainerOrigin + path,
+			ReadOnly: true,
+		})
 	}
 
-	return cmd, dockerArgs, "", "latest", false, nil
+	return ModSpec{
+		Cmd:     cmd,
+		Mounts:  mounts,
+		Version: "latest",
+	}, nil
 }
 
-func (r RemoveNavmesh) Validate(otherMods []Mod) error {
-	return nil
+func (r RemoveNavmesh) EnabledByDefault() bool {
+	return false
 }
 
-func (r RemoveNavmesh) EnabledByDefault() bool {
+func (r RemoveNavmesh) installsToModsDir() bool {
 	return false
 }
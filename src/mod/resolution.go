@@ -0,0 +1,57 @@
+package mod
+
+import (
+	"context"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod/thunderstore"
+)
+
+// thunderstoreInstaller is implemented by mods installed from thunderstore, so their packages are resolved together
+type thunderstoreInstaller interface {
+	thunderstoreRequest() (thunderstore.Request, bool)
+}
+
+func thunderstoreRequest(m Mod) (thunderstore.Request, bool) {
+	installer, ok := m.(thunderstoreInstaller)
+	if !ok {
+		return thunderstore.Request{}, false
+	}
+	return installer.thunderstoreRequest()
+}
+
+type thunderstoreResolutionsKey struct{}
+
+// ResolveThunderstore resolves packages of the mods installed from thunderstore together, so dependency version
+// conflicts between the mods are reported, and shared dependencies are installed once, by the first mod. Specs
+// generated with the returned context use the resolution
+func ResolveThunderstore(ctx context.Context, mods []Mod) (context.Context, error) {
+	var requests []thunderstore.Request
+	for _, m := range mods {
+		if request, ok := thunderstoreRequest(m); ok {
+			requests = append(requests, request)
+		}
+	}
+	if len(requests) == 0 {
+		return ctx, nil
+	}
+	resolutions, err := thunderstore.ResolveAll(ctx, requests)
+	if err != nil {
+		return ctx, err
+	}
+	resolved := make(map[thunderstore.Request]thunderstore.Resolution, len(requests))
+	for i, request := range requests {
+		if _, ok := resolved[request]; !ok {
+			resolved[request] = resolutions[i]
+		}
+	}
+	return context.WithValue(ctx, thunderstoreResolutionsKey{}, resolved), nil
+}
+
+func resolvedThunderstorePackage(ctx context.Context, request thunderstore.Request) (thunderstore.Resolution, bool) {
+	resolved, ok := ctx.Value(thunderstoreResolutionsKey{}).(map[thunderstore.Request]thunderstore.Resolution)
+	if !ok {
+		return thunderstore.Resolution{}, false
+	}
+	resolution, ok := resolved[request]
+	return resolution, ok
+}
@@ -0,0 +1,144 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Relations declares how a mod relates to other mods, by their names
+type Relations struct {
+	// ConflictsWith lists mods that can't be enabled together with the mod
+	ConflictsWith []string
+	// Requires lists mods that have to be enabled for the mod to work
+	Requires []string
+	// Implies lists mods that are enabled along with the mod
+	Implies []string
+}
+
+func (r Relations) isEmpty() bool {
+	return len(r.ConflictsWith) == 0 && len(r.Requires) == 0 && len(r.Implies) == 0
+}
+
+// relations maps mod name to its declared relations
+var relations = map[string]Relations{
+	"titan_debug": {
+		ConflictsWith: []string{"rebalanced_lts_mod", RebalancedLtsModTest},
+	},
+	"rebalanced_lts_mod": {
+		// release, and pre-release versions of the mod can't be installed together
+		ConflictsWith: []string{RebalancedLtsModTest},
+	},
+}
+
+// RelationsOf returns relations of the mod. Conflicts are symmetric, so conflicts declared by other mods are
+// included as well.
+func RelationsOf(name string) Relations {
+	declared := relations[name]
+	r := Relations{
+		Requires: append([]string(nil), declared.Requires...),
+		Implies:  append([]string(nil), declared.Implies...),
+	}
+	conflicting := make(map[string]bool)
+	for _, conflict := range declared.ConflictsWith {
+		conflicting[conflict] = true
+	}
+	for other, otherRelations := range relations {
+		for _, conflict := range otherRelations.ConflictsWith {
+			if conflict == name {
+				conflicting[other] = true
+			}
+		}
+	}
+	for conflict := range conflicting {
+		r.ConflictsWith = append(r.ConflictsWith, conflict)
+	}
+	sort.Strings(r.ConflictsWith)
+	return r
+}
+
+// validateRelations checks that relations only reference known mods
+func validateRelations(name string, r Relations) error {
+	for _, related := range [][]string{r.ConflictsWith, r.Requires, r.Implies} {
+		for _, other := range related {
+			if _, ok := ByName[other]; !ok {
+				return fmt.Errorf("%w: %s references unknown mod %s", ErrInvalidDefinition, name, other)
+			}
+			if other == name {
+				return fmt.Errorf("%w: %s references itself", ErrInvalidDefinition, name)
+			}
+		}
+	}
+	return nil
+}
+
+var ErrConflictingMods = errors.New("conflicting mods")
+var ErrMissingRequiredMod = errors.New("missing required mod")
+
+// RuleErrors lists every violated mod rule
+type RuleErrors []error
+
+func (e RuleErrors) Error() string {
+	messages := make([]string, len(e))
+	for idx, err := range e {
+		messages[idx] = "- " + err.Error()
+	}
+	return fmt.Sprintf("invalid mod selection. Please explicitly enable/disable the mods:\n%s", strings.Join(messages, "\n"))
+}
+
+// Is reports whether any of the violations matches the target
+func (e RuleErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveMods enables mods implied by the enabled ones, and checks conflicts, and requirements between them. Sorted
+// names of the mods to install are returned. All violated rules are reported at once with RuleErrors.
+func ResolveMods(enabled []string) ([]string, error) {
+	isEnabled := make(map[string]bool, len(enabled))
+	queue := append([]string(nil), enabled...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if isEnabled[name] {
+			continue
+		}
+		isEnabled[name] = true
+		queue = append(queue, relations[name].Implies...)
+	}
+
+	names := make([]string, 0, len(isEnabled))
+	for name := range isEnabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var violations RuleErrors
+	reported := make(map[string]bool)
+	for _, name := range names {
+		r := relations[name]
+		for _, conflict := range r.ConflictsWith {
+			pair := []string{name, conflict}
+			sort.Strings(pair)
+			key := strings.Join(pair, ",")
+			if isEnabled[conflict] && !reported[key] {
+				reported[key] = true
+				violations = append(violations, fmt.Errorf("%w: cannot have both %s, and %s enabled", ErrConflictingMods, pair[0], pair[1]))
+			}
+		}
+		for _, required := range r.Requires {
+			if !isEnabled[required] {
+				violations = append(violations, fmt.Errorf("%w: %s requires %s to be enabled", ErrMissingRequiredMod, name, required))
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return nil, violations
+	}
+	return names, nil
+}
@@ -0,0 +1,284 @@
+package mod
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"al.essio.dev/pkg/shellescape"
+	"github.com/google/go-github/v42/github"
+)
+
+const defaultModsDir = "mods"
+
+// northstarModsDir is the directory of the mods shipped with the server, inside the server container
+const northstarModsDir = "/usr/lib/northstar/R2Northstar/mods/"
+
+// northstarCoreMods are the mods shipped with the server, that forks of NorthstarMods replace
+var northstarCoreMods = []string{
+	"Northstar.Client",
+	"Northstar.Custom",
+	"Northstar.CustomServers",
+}
+
+// GithubReleaseMod installs a zip asset of a github release
+type GithubReleaseMod struct {
+	Owner string
+	Repo  string
+	// Tag pins the release. Latest release is installed when empty
+	Tag        string
+	PreRelease bool
+	// Asset is a glob matched against asset names, where {tag} is replaced with the release tag. First zip asset
+	// is installed when empty
+	Asset string
+	// ModsDir is the directory in the archive, that contains mod folders
+	ModsDir string
+	// OverrideMods lists mod folders, that are mounted over the mods shipped with the server, instead of being
+	// installed to the mods directory
+	OverrideMods     []string
+	RequiredByClient bool
+	Enabled          bool
+}
+
+var ErrNoSuchAsset = fmt.Errorf("no matching release asset")
+
+func (g GithubReleaseMod) release(ctx context.Context) (*github.RepositoryRelease, error) {
+	client := github.NewClient(nil)
+	if g.Tag != "" {
+		release, _, err := client.Repositories.GetReleaseByTag(ctx, g.Owner, g.Repo, g.Tag)
+		if err != nil {
+			return nil, fmt.Errorf("error getting release %s: %w", g.Tag, err)
+		}
+		return release, nil
+	}
+
+	tag, err := latestGithubReleaseTag(ctx, g.Owner, g.Repo, g.PreRelease)
+	if err != nil {
+		return nil, err
+	}
+	release, _, err := client.Repositories.GetReleaseByTag(ctx, g.Owner, g.Repo, tag)
+	if err != nil {
+		return nil, fmt.Errorf("error getting release %s: %w", tag, err)
+	}
+	return release, nil
+}
+
+func (g GithubReleaseMod) asset(release *github.RepositoryRelease) (string, error) {
+	pattern := strings.ReplaceAll(g.Asset, "{tag}", release.GetTagName())
+	for _, asset := range release.Assets {
+		name := asset.GetName()
+		if pattern == "" && strings.HasSuffix(name, ".zip") {
+			return asset.GetBrowserDownloadURL(), nil
+		}
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return asset.GetBrowserDownloadURL(), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s/%s %s", ErrNoSuchAsset, g.Owner, g.Repo, release.GetTagName())
+}
+
+func (g GithubReleaseMod) Spec(ctx context.Context) (ModSpec, error) {
+	release, err := g.release(ctx)
+	if err != nil {
+		return ModSpec{}, err
+	}
+	link, err := g.asset(release)
+	if err != nil {
+		return ModSpec{}, err
+	}
+	zipName := g.Owner + "." + g.Repo
+	cmd, checksum, err := cmdVerifiedWgetZipBuilder(ctx, link, zipName)
+	if err != nil {
+		return ModSpec{}, err
+	}
+	installCmd, mounts, modsDir := installMods(workPath(ctx, zipName, modsDirOrDefault(g.ModsDir)), g.OverrideMods)
+	return ModSpec{
+		Cmd:              cmd + cmdUnzipBuilderWithDst(ctx, zipName) + installCmd,
+		Mounts:           mounts,
+		ModsDir:          modsDir,
+		RequiredByClient: g.RequiredByClient,
+		DownloadLink:     link,
+		Version:          release.GetTagName(),
+		Checksum:         checksum,
+	}, nil
+}
+
+func (g GithubReleaseMod) EnabledByDefault() bool {
+	return g.Enabled
+}
+
+func (g GithubReleaseMod) installsToModsDir() bool {
+	return len(g.OverrideMods) == 0
+}
+
+// GitRefMod installs mods from a branch, or a commit of a git repository
+type GitRefMod struct {
+	// Dir is the directory the repository is cloned to
+	Dir string
+	URL string
+	// Ref is the branch, or tag to clone
+	Ref string
+	// Commit pins the commit, and takes precedence over Ref
+	Commit  string
+	ModsDir string
+	// OverrideMods lists mod folders, that are mounted over the mods shipped with the server, instead of being
+	// installed to the mods directory
+	OverrideMods []string
+	// RequiredByClient is true, when clients need the same mods to join
+	RequiredByClient bool
+	Enabled          bool
+}
+
+var ErrInvalidGitRef = fmt.Errorf("invalid git ref")
+
+var gitRefRegexp = regexp.MustCompile(`^[A-Za-z0-9][-_./A-Za-z0-9]*$`)
+var gitCommitRegexp = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+func (g GitRefMod) cloneCmd(ctx context.Context) (string, error) {
+	dir := shellescape.Quote(workPath(ctx, g.Dir))
+	url := shellescape.Quote(g.URL)
+	builder := strings.Builder{}
+	if g.Commit != "" {
+		if !gitCommitRegexp.MatchString(g.Commit) {
+			return "", fmt.Errorf("%w: commit %s", ErrInvalidGitRef, g.Commit)
+		}
+		builder.WriteString(fmt.Sprintf("git init -q %s", dir))
+		builder.WriteString("\n")
+		builder.WriteString(fmt.Sprintf("git -C %s fetch --depth 1 %s %s", dir, url, g.Commit))
+		builder.WriteString("\n")
+		builder.WriteString(fmt.Sprintf("git -C %s checkout -q FETCH_HEAD", dir))
+		builder.WriteString("\n")
+		return builder.String(), nil
+	}
+
+	if !gitRefRegexp.MatchString(g.Ref) || strings.Contains(g.Ref, "..") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidGitRef, g.Ref)
+	}
+	builder.WriteString(fmt.Sprintf("git clone --depth 1 -b %s %s %s", shellescape.Quote(g.Ref), url, dir))
+	builder.WriteString("\n")
+	return builder.String(), nil
+}
+
+func (g GitRefMod) Spec(ctx context.Context) (ModSpec, error) {
+	cmd, err := g.cloneCmd(ctx)
+	if err != nil {
+		return ModSpec{}, err
+	}
+	installCmd, mounts, modsDir := installMods(workPath(ctx, g.Dir, modsDirOrDefault(g.ModsDir)), g.OverrideMods)
+	version := g.Ref
+	if g.Commit != "" {
+		version = g.Commit
+	}
+	return ModSpec{
+		Cmd:              cmd + installCmd,
+		Mounts:           mounts,
+		ModsDir:          modsDir,
+		RequiredByClient: g.RequiredByClient,
+		DownloadLink:     g.URL,
+		Version:          version,
+	}, nil
+}
+
+func (g GitRefMod) EnabledByDefault() bool {
+	return g.Enabled
+}
+
+func (g GitRefMod) installsToModsDir() bool {
+	return len(g.OverrideMods) == 0
+}
+
+// URLMod installs mods from a zip archive
+type URLMod struct {
+	// Dir is the directory the archive is extracted to
+	Dir              string
+	URL              string
+	Version          string
+	ModsDir          string
+	RequiredByClient bool
+	Enabled          bool
+}
+
+func (u URLMod) Spec(ctx context.Context) (ModSpec, error) {
+	cmd, checksum, err := cmdZipModBuilder(ctx, u.URL, u.Dir, modsDirOrDefault(u.ModsDir))
+	if err != nil {
+		return ModSpec{}, err
+	}
+	return ModSpec{
+		Cmd:              cmd,
+		ModsDir:          workPath(ctx, u.Dir, modsDirOrDefault(u.ModsDir)),
+		RequiredByClient: u.RequiredByClient,
+		DownloadLink:     u.URL,
+		Version:          u.Version,
+		Checksum:         checksum,
+	}, nil
+}
+
+func (u URLMod) EnabledByDefault() bool {
+	return u.Enabled
+}
+
+// GithubBranchPrefix prefixes custom mods, that are installed from a github branch
+const GithubBranchPrefix = "github.com/"
+
+var ErrInvalidGithubBranch = fmt.Errorf("invalid github branch")
+
+var githubBranchRegexp = regexp.MustCompile(`^([-A-Za-z0-9]+)/([-_.A-Za-z0-9]+)@([A-Za-z0-9][-_./A-Za-z0-9]*)$`)
+
+// ParseGithubBranch parses github branch passed as Owner/Repo@branch
+func ParseGithubBranch(branch string) (string, string, string, error) {
+	matches := githubBranchRegexp.FindStringSubmatch(strings.TrimPrefix(branch, GithubBranchPrefix))
+	if matches == nil || strings.Contains(matches[3], "..") {
+		return "", "", "", fmt.Errorf("%w: %s. Must be formatted as Owner/Repo@branch", ErrInvalidGithubBranch, branch)
+	}
+	return matches[1], matches[2], matches[3], nil
+}
+
+// CustomGithubBranchMod returns mod for a branch of a NorthstarMods fork, passed as github.com/Owner/Repo@branch.
+// Core mods of the branch replace the ones shipped with the server
+func CustomGithubBranchMod(option string) (Mod, error) {
+	owner, repo, branch, err := ParseGithubBranch(option)
+	if err != nil {
+		return nil, err
+	}
+	return GitRefMod{
+		Dir:          strings.ReplaceAll(fmt.Sprintf("%s.%s.%s", owner, repo, branch), "/", "."),
+		URL:          fmt.Sprintf("https://github.com/%s/%s.git", owner, repo),
+		Ref:          branch,
+		ModsDir:      ".",
+		OverrideMods: northstarCoreMods,
+	}, nil
+}
+
+// CustomMod returns mod for a custom mod option, that isn't one of the known mods
+func CustomMod(option string) (Mod, error) {
+	if strings.HasPrefix(option, GithubBranchPrefix) {
+		return CustomGithubBranchMod(option)
+	}
+	return CustomThunderstoreMod(option), nil
+}
+
+// installMods copies mod folders from dir to the mods directory, and returns the directory they are copied from.
+// When overrideMods is set, only listed folders are installed, by mounting them over the mods shipped with the server
+func installMods(dir string, overrideMods []string) (string, []Mount, string) {
+	if len(overrideMods) == 0 {
+		return fmt.Sprintf("cp -r %s/* /mods/\n", dir), nil, dir
+	}
+	mounts := make([]Mount, 0, len(overrideMods))
+	for _, modDir := range overrideMods {
+		mounts = append(mounts, Mount{
+			Source:   path.Join(dir, modDir),
+			Target:   northstarModsDir + modDir,
+			ReadOnly: true,
+		})
+	}
+	return "", mounts, ""
+}
+
+func modsDirOrDefault(modsDir string) string {
+	if modsDir == "" {
+		return defaultModsDir
+	}
+	return modsDir
+}
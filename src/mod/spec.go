@@ -0,0 +1,74 @@
+package mod
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ModSpec describes how a mod is installed on the server
+type ModSpec struct {
+	// Cmd is run on the host, before the server container is started
+	Cmd string
+	// Mounts are bind mounted into the server container
+	Mounts []Mount
+	// Env is passed to the server container
+	Env map[string]string
+	// ConVars are appended to the server launch arguments
+	ConVars          map[string]string
+	RequiredByClient bool
+	DownloadLink     string
+	Version          string
+	// Checksum is SHA-256 of the downloaded archive
+	Checksum string
+	// Dependencies are full names of the installed dependencies
+	Dependencies []string
+	// ThunderstorePackage is Owner-Name of the package, for mods installed from thunderstore
+	ThunderstorePackage string
+	// Files are written to the mods directory, after all mods are installed. Keys are paths relative to the mods
+	// directory
+	Files map[string]string
+	// ModsDir is the host directory, that the mod folders are copied from to the mods directory. It is empty for mods,
+	// that have no folders in the mods directory
+	ModsDir string
+}
+
+type Mount struct {
+	Source   string
+	Target   string
+	ReadOnly bool
+}
+
+// DockerArgs returns docker run arguments for the mounts, and env vars of the spec
+func (s ModSpec) DockerArgs() string {
+	args := make([]string, 0, len(s.Mounts)+len(s.Env))
+	for _, mount := range s.Mounts {
+		arg := fmt.Sprintf("type=bind,source=%s,target=%s", mount.Source, mount.Target)
+		if mount.ReadOnly {
+			arg += ",readonly"
+		}
+		args = append(args, fmt.Sprintf("--mount \"%s\"", arg))
+	}
+	for _, key := range sortedKeys(s.Env) {
+		args = append(args, fmt.Sprintf("--env \"%s=%s\"", key, s.Env[key]))
+	}
+	return strings.Join(args, " ")
+}
+
+// LaunchArgs returns the ConVars formatted as server launch arguments
+func (s ModSpec) LaunchArgs() string {
+	args := make([]string, 0, len(s.ConVars))
+	for _, key := range sortedKeys(s.ConVars) {
+		args = append(args, fmt.Sprintf("+%s %s", key, s.ConVars[key]))
+	}
+	return strings.Join(args, " ")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
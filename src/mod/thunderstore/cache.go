@@ -0,0 +1,142 @@
+package thunderstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// CacheFile persists the package index, so it's available right after restart. Empty disables persistence
+	CacheFile string `default:"thunderstore_index.json"`
+	// CacheTTLSeconds is how long the index is used, before it is revalidated with thunderstore
+	CacheTTLSeconds uint `default:"600"`
+}
+
+// indexCache caches the package index. Expired index is revalidated with ETag, and If-Modified-Since headers, and
+// stale index is used when thunderstore is unreachable.
+type indexCache struct {
+	lock *sync.Mutex
+	ttl  time.Duration
+	file string
+	// loaded is true once the index was read from the file
+	loaded bool
+	cached cachedIndex
+}
+
+type cachedIndex struct {
+	Packages     []Package `json:"packages"`
+	ETag         string    `json:"etag"`
+	LastModified string    `json:"lastModified"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+}
+
+const defaultCacheTTL = 10 * time.Minute
+
+var index = &indexCache{
+	lock: &sync.Mutex{},
+	ttl:  defaultCacheTTL,
+}
+
+// Configure sets up the package index cache. It should be called before the index is used.
+func Configure(c Config) {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	index.file = c.CacheFile
+	index.ttl = time.Duration(c.CacheTTLSeconds) * time.Second
+	if index.ttl == 0 {
+		index.ttl = defaultCacheTTL
+	}
+}
+
+func (c *indexCache) get(ctx context.Context) ([]Package, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.loaded {
+		c.loaded = true
+		if err := c.load(); err != nil {
+			log.Println(fmt.Sprintf("unable to load thunderstore index from %s: %v", c.file, err))
+		}
+	}
+
+	if c.cached.Packages != nil && time.Since(c.cached.FetchedAt) < c.ttl {
+		return c.cached.Packages, nil
+	}
+
+	packages, etag, lastModified, err := fetchPackages(ctx, c.cached.ETag, c.cached.LastModified)
+	switch {
+	case errors.Is(err, errNotModified) && c.cached.Packages != nil:
+		c.cached.FetchedAt = time.Now()
+	case err != nil:
+		if c.cached.Packages != nil {
+			log.Println(fmt.Sprintf("unable to refresh thunderstore index, using the one from %s: %v", c.cached.FetchedAt.Format(time.RFC3339), err))
+			return c.cached.Packages, nil
+		}
+		return nil, err
+	default:
+		c.cached = cachedIndex{
+			Packages:     packages,
+			ETag:         etag,
+			LastModified: lastModified,
+			FetchedAt:    time.Now(),
+		}
+	}
+
+	if err := c.save(); err != nil {
+		log.Println(fmt.Sprintf("unable to save thunderstore index to %s: %v", c.file, err))
+	}
+	return c.cached.Packages, nil
+}
+
+func (c *indexCache) load() error {
+	if c.file == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(c.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	var cached cachedIndex
+	err = json.Unmarshal(data, &cached)
+	if err != nil {
+		return fmt.Errorf("unmarshal error: %w", err)
+	}
+	c.cached = cached
+	return nil
+}
+
+// save writes the index to a temporary file first, so a crash doesn't leave a truncated index behind
+func (c *indexCache) save() error {
+	if c.file == "" {
+		return nil
+	}
+	data, err := json.Marshal(c.cached)
+	if err != nil {
+		return fmt.Errorf("marshal error: %w", err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.file), filepath.Base(c.file)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	return os.Rename(tmp.Name(), c.file)
+}
@@ -0,0 +1,267 @@
+package thunderstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// coreOwner owns packages that are shipped with Northstar itself, and don't have to be installed
+const coreOwner = "northstar"
+
+// Dependency is a reference to a package version, formatted as Owner-Name-Version
+type Dependency struct {
+	Owner   string
+	Name    string
+	Version string
+}
+
+func (d Dependency) packageKey() string {
+	return d.Owner + "-" + d.Name
+}
+
+func (d Dependency) String() string {
+	return d.packageKey() + "-" + d.Version
+}
+
+var ErrInvalidDependency = errors.New("invalid dependency")
+
+// ParseDependency parses Owner-Name-Version string. Package names can't contain -, owners can.
+func ParseDependency(dependency string) (Dependency, error) {
+	versionIdx := strings.LastIndex(dependency, "-")
+	if versionIdx == -1 {
+		return Dependency{}, fmt.Errorf("%w: %s", ErrInvalidDependency, dependency)
+	}
+	nameIdx := strings.LastIndex(dependency[:versionIdx], "-")
+	if nameIdx <= 0 {
+		return Dependency{}, fmt.Errorf("%w: %s", ErrInvalidDependency, dependency)
+	}
+	return Dependency{
+		Owner:   dependency[:nameIdx],
+		Name:    dependency[nameIdx+1 : versionIdx],
+		Version: dependency[versionIdx+1:],
+	}, nil
+}
+
+// Resolved is a package version selected for installation
+type Resolved struct {
+	Package Package
+	Version Version
+}
+
+// Resolve returns the requested package version, along with its dependencies in install order. Latest version is
+// selected when versionNumber is empty.
+func Resolve(ctx context.Context, name string, versionNumber string) (Resolved, []Resolved, error) {
+	resolutions, err := ResolveAll(ctx, []Request{{Name: name, Version: versionNumber}})
+	if err != nil {
+		return Resolved{}, nil, err
+	}
+	return resolutions[0].Resolved, resolutions[0].Dependencies, nil
+}
+
+// Request is a package to resolve, named Name, or Owner/Name. Latest version is selected when Version is empty
+type Request struct {
+	Name    string
+	Version string
+}
+
+// Resolution is a requested package version, along with the dependencies it installs
+type Resolution struct {
+	Resolved
+	// Dependencies are in install order. Dependencies, that are requested themselves, or installed by an earlier
+	// request, aren't listed again
+	Dependencies []Resolved
+}
+
+var ErrDependencyCycle = errors.New("dependency cycle")
+var ErrVersionConflict = errors.New("dependency version conflict")
+
+// requestedBy is recorded as requiredBy of the requested packages
+const requestedBy = "requested"
+
+type resolver struct {
+	packages map[string]Package
+	// selected maps package key to the selected dependency version
+	selected map[string]Dependency
+	// requiredBy maps package key to the package that required the selected version
+	requiredBy map[string]string
+	// requested are keys of the requested packages, whose versions are fixed
+	requested map[string]bool
+}
+
+// ResolveAll resolves the packages together, so packages that depend on different major versions of the same
+// package conflict, even when they are requested separately. When packages depend on different versions of the same
+// package, the highest one is selected, as long as the major versions match. Resolutions are in the order of the
+// requests
+func ResolveAll(ctx context.Context, requests []Request) ([]Resolution, error) {
+	packages, err := GetPackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r := resolver{
+		packages:   make(map[string]Package, len(packages)),
+		selected:   make(map[string]Dependency),
+		requiredBy: make(map[string]string),
+		requested:  make(map[string]bool),
+	}
+	for _, pkg := range packages {
+		r.packages[pkg.Owner+"-"+pkg.Name] = pkg
+	}
+
+	resolutions := make([]Resolution, len(requests))
+	for i, request := range requests {
+		pkg, err := findPackageByName(packages, request.Name)
+		if err != nil {
+			return nil, err
+		}
+		if pkg.IsDeprecated {
+			return nil, fmt.Errorf("%w: %s", ErrDeprecatedPackage, pkg.FullName)
+		}
+		var version Version
+		if request.Version == "" {
+			version, err = GetLatestPackageVersion(pkg)
+		} else {
+			version, err = GetPackageVersion(pkg, request.Version)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		key := pkg.Owner + "-" + pkg.Name
+		if selected, ok := r.selected[key]; ok && selected.Version != version.VersionNumber {
+			return nil, fmt.Errorf("%w: %s is requested, along with %s", ErrVersionConflict, version.FullName, selected)
+		}
+		r.selected[key] = Dependency{Owner: pkg.Owner, Name: pkg.Name, Version: version.VersionNumber}
+		r.requiredBy[key] = requestedBy
+		r.requested[key] = true
+		resolutions[i].Resolved = Resolved{Package: pkg, Version: version}
+	}
+
+	for _, resolution := range resolutions {
+		pkg := resolution.Package
+		key := pkg.Owner + "-" + pkg.Name
+		if err := r.selectVersions(resolution.Version, resolution.Version.FullName, []string{key}); err != nil {
+			return nil, fmt.Errorf("unable to resolve dependencies of %s: %w", resolution.Version.FullName, err)
+		}
+	}
+
+	// versions could have changed while walking, so install order is computed from the final selection
+	visited := make(map[string]bool, len(r.requested))
+	for key := range r.requested {
+		visited[key] = true
+	}
+	for i := range resolutions {
+		err := r.installOrder(resolutions[i].Version, visited, &resolutions[i].Dependencies)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve dependencies of %s: %w", resolutions[i].Version.FullName, err)
+		}
+	}
+	return resolutions, nil
+}
+
+func (r *resolver) selectVersions(version Version, requiredBy string, path []string) error {
+	for _, dependencyString := range version.Dependencies {
+		dependency, err := ParseDependency(dependencyString)
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(dependency.Owner, coreOwner) {
+			continue
+		}
+
+		key := dependency.packageKey()
+		for _, p := range path {
+			if p == key {
+				return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(append(path, key), " -> "))
+			}
+		}
+
+		if selected, ok := r.selected[key]; ok {
+			cmp, sameMajor := compareVersions(dependency.Version, selected.Version)
+			if r.requested[key] && (!sameMajor || cmp > 0) {
+				return fmt.Errorf("%w: %s requires %s, but %s is requested", ErrVersionConflict, requiredBy, dependency, selected)
+			}
+			if !sameMajor {
+				return fmt.Errorf("%w: %s requires %s, but %s requires %s", ErrVersionConflict, requiredBy, dependency, r.requiredBy[key], selected)
+			}
+			if cmp <= 0 {
+				continue
+			}
+		}
+		r.selected[key] = dependency
+		r.requiredBy[key] = requiredBy
+
+		dependencyVersion, err := r.version(dependency)
+		if err != nil {
+			return err
+		}
+		err = r.selectVersions(dependencyVersion, dependency.String(), append(path, key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *resolver) installOrder(version Version, visited map[string]bool, order *[]Resolved) error {
+	for _, dependencyString := range version.Dependencies {
+		dependency, err := ParseDependency(dependencyString)
+		if err != nil {
+			return err
+		}
+		key := dependency.packageKey()
+		if strings.EqualFold(dependency.Owner, coreOwner) || visited[key] {
+			continue
+		}
+		visited[key] = true
+
+		selected := r.selected[key]
+		dependencyVersion, err := r.version(selected)
+		if err != nil {
+			return err
+		}
+		err = r.installOrder(dependencyVersion, visited, order)
+		if err != nil {
+			return err
+		}
+		*order = append(*order, Resolved{Package: r.packages[key], Version: dependencyVersion})
+	}
+	return nil
+}
+
+func (r *resolver) version(dependency Dependency) (Version, error) {
+	pkg, ok := r.packages[dependency.packageKey()]
+	if !ok {
+		return Version{}, fmt.Errorf("%w: %s", ErrNoSuchPackage, dependency.packageKey())
+	}
+	if pkg.IsDeprecated {
+		return Version{}, fmt.Errorf("%w: %s", ErrDeprecatedPackage, pkg.FullName)
+	}
+	return GetPackageVersion(pkg, dependency.Version)
+}
+
+// compareVersions compares major.minor.patch versions, and reports whether the major versions match
+func compareVersions(a, b string) (int, bool) {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	sameMajor := aParts[0] == bParts[0]
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		if aErr != nil || bErr != nil {
+			if cmp := strings.Compare(aParts[i], bParts[i]); cmp != 0 {
+				return cmp, sameMajor
+			}
+			continue
+		}
+		if aNum != bNum {
+			if aNum > bNum {
+				return 1, sameMajor
+			}
+			return -1, sameMajor
+		}
+	}
+	return len(aParts) - len(bParts), sameMajor
+}
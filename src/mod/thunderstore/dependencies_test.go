@@ -0,0 +1,88 @@
+package thunderstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testPackage(owner string, name string, deprecated bool, versions map[string][]string) Package {
+	pkg := Package{Owner: owner, Name: name, FullName: owner + "-" + name, IsDeprecated: deprecated}
+	for number, dependencies := range versions {
+		pkg.Versions = append(pkg.Versions, Version{
+			Name:          name,
+			FullName:      owner + "-" + name + "-" + number,
+			VersionNumber: number,
+			Dependencies:  dependencies,
+			IsActive:      true,
+		})
+	}
+	return pkg
+}
+
+// useIndex replaces the package index, so packages are resolved without thunderstore
+func useIndex(t *testing.T, packages ...Package) {
+	t.Helper()
+	previous := index
+	index = &indexCache{
+		lock:   &sync.Mutex{},
+		ttl:    time.Hour,
+		loaded: true,
+		cached: cachedIndex{Packages: packages, FetchedAt: time.Now()},
+	}
+	t.Cleanup(func() { index = previous })
+}
+
+func TestResolveAllConflictAcrossRequests(t *testing.T) {
+	useIndex(t,
+		testPackage("A", "Old", false, map[string][]string{"1.0.0": {"Lib-Shared-1.0.0"}}),
+		testPackage("B", "New", false, map[string][]string{"1.0.0": {"Lib-Shared-2.0.0"}}),
+		testPackage("Lib", "Shared", false, map[string][]string{"1.0.0": nil, "2.0.0": nil}),
+	)
+	_, err := ResolveAll(context.Background(), []Request{{Name: "A/Old"}, {Name: "B/New"}})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected %v, got %v", ErrVersionConflict, err)
+	}
+}
+
+func TestResolveAllSharedDependencies(t *testing.T) {
+	useIndex(t,
+		testPackage("A", "First", false, map[string][]string{"1.0.0": {"Lib-Shared-1.0.0", "northstar-Northstar-1.10.0"}}),
+		testPackage("B", "Second", false, map[string][]string{"1.0.0": {"Lib-Shared-1.2.0", "A-First-1.0.0"}}),
+		testPackage("Lib", "Shared", false, map[string][]string{"1.0.0": nil, "1.2.0": nil}),
+	)
+	resolutions, err := ResolveAll(context.Background(), []Request{{Name: "A/First"}, {Name: "B/Second"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolutions[0].Dependencies) != 1 || resolutions[0].Dependencies[0].Version.VersionNumber != "1.2.0" {
+		t.Errorf("expected the highest version of the shared dependency, got %+v", resolutions[0].Dependencies)
+	}
+	if len(resolutions[1].Dependencies) != 0 {
+		t.Errorf("expected dependencies installed by other requests to be skipped, got %+v", resolutions[1].Dependencies)
+	}
+}
+
+func TestResolveAllRequestedVersionIsFixed(t *testing.T) {
+	useIndex(t,
+		testPackage("A", "Pinned", false, map[string][]string{"1.0.0": nil, "1.1.0": nil}),
+		testPackage("B", "Dependent", false, map[string][]string{"1.0.0": {"A-Pinned-1.1.0"}}),
+	)
+	_, err := ResolveAll(context.Background(), []Request{{Name: "A/Pinned", Version: "1.0.0"}, {Name: "B/Dependent"}})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected %v, got %v", ErrVersionConflict, err)
+	}
+}
+
+func TestResolveAllDeprecatedDependency(t *testing.T) {
+	useIndex(t,
+		testPackage("A", "Mod", false, map[string][]string{"1.0.0": {"Lib-Gone-1.0.0"}}),
+		testPackage("Lib", "Gone", true, map[string][]string{"1.0.0": nil}),
+	)
+	_, err := ResolveAll(context.Background(), []Request{{Name: "A/Mod"}})
+	if !errors.Is(err, ErrDeprecatedPackage) {
+		t.Errorf("expected %v, got %v", ErrDeprecatedPackage, err)
+	}
+}
@@ -0,0 +1,86 @@
+package thunderstore
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ProfileMod is a package version in a mod manager profile
+type ProfileMod struct {
+	// Package is Owner-Name of the package
+	Package string
+	Version string
+}
+
+// profileExport is export.r2x file of the r2modman profile export
+type profileExport struct {
+	ProfileName string             `yaml:"profileName"`
+	Mods        []profileExportMod `yaml:"mods"`
+}
+
+type profileExportMod struct {
+	Name    string               `yaml:"name"`
+	Version profileExportVersion `yaml:"version"`
+	Enabled bool                 `yaml:"enabled"`
+}
+
+type profileExportVersion struct {
+	Major int `yaml:"major"`
+	Minor int `yaml:"minor"`
+	Patch int `yaml:"patch"`
+}
+
+var ErrInvalidVersion = errors.New("invalid version")
+
+func parseProfileVersion(version string) (profileExportVersion, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return profileExportVersion{}, fmt.Errorf("%w: %s", ErrInvalidVersion, version)
+	}
+	numbers := make([]int, len(parts))
+	for idx, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return profileExportVersion{}, fmt.Errorf("%w: %s", ErrInvalidVersion, version)
+		}
+		numbers[idx] = number
+	}
+	return profileExportVersion{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// ExportProfile returns r2modman profile export (.r2z), that can be imported with "Import profile from file"
+func ExportProfile(profileName string, mods []ProfileMod) ([]byte, error) {
+	export := profileExport{ProfileName: profileName}
+	for _, m := range mods {
+		version, err := parseProfileVersion(m.Version)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Package, err)
+		}
+		export.Mods = append(export.Mods, profileExportMod{Name: m.Package, Version: version, Enabled: true})
+	}
+
+	data, err := yaml.Marshal(export)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal profile: %w", err)
+	}
+
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+	file, err := archive.Create("export.r2x")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create profile archive: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write profile archive: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write profile archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package thunderstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// IsClientSide reports whether clients need the package installed to join the server
+func (p Package) IsClientSide() bool {
+	for _, category := range p.Categories {
+		if strings.Contains(category, "Client-side") {
+			return true
+		}
+	}
+	return false
+}
+
+// Downloads returns downloads of all versions of the package
+func (p Package) Downloads() int {
+	downloads := 0
+	for _, version := range p.Versions {
+		downloads += version.Downloads
+	}
+	return downloads
+}
+
+// matchScore scores how well the package matches every term of the query. Name matches weigh the most, followed by
+// owner, category, and description matches. Zero is returned, when any of the terms doesn't match.
+func matchScore(pkg Package, terms []string) int {
+	description := ""
+	if len(pkg.Versions) > 0 {
+		description = strings.ToLower(pkg.Versions[0].Description)
+	}
+	name := strings.ToLower(pkg.Name)
+	owner := strings.ToLower(pkg.Owner)
+
+	score := 0
+	for _, term := range terms {
+		termScore := 0
+		switch {
+		case name == term:
+			termScore = 8
+		case strings.Contains(name, term):
+			termScore = 4
+		case strings.Contains(owner, term):
+			termScore = 3
+		}
+		for _, category := range pkg.Categories {
+			if strings.Contains(strings.ToLower(category), term) {
+				termScore += 2
+				break
+			}
+		}
+		if strings.Contains(description, term) {
+			termScore++
+		}
+		if termScore == 0 {
+			return 0
+		}
+		score += termScore
+	}
+	return score
+}
+
+// SearchPackages returns packages matching the query by name, owner, description and category. Best matches are
+// returned first, ties are broken by rating, and downloads.
+func SearchPackages(ctx context.Context, query string, limit int) ([]Package, error) {
+	packages, err := GetPackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	type match struct {
+		pkg       Package
+		score     int
+		downloads int
+	}
+	var matches []match
+	for _, pkg := range packages {
+		if pkg.HasNsfwContent {
+			continue
+		}
+		if score := matchScore(pkg, terms); score > 0 {
+			matches = append(matches, match{pkg: pkg, score: score, downloads: pkg.Downloads()})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		if matches[i].pkg.RatingScore != matches[j].pkg.RatingScore {
+			return matches[i].pkg.RatingScore > matches[j].pkg.RatingScore
+		}
+		return matches[i].downloads > matches[j].downloads
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result := make([]Package, len(matches))
+	for idx, m := range matches {
+		result[idx] = m.pkg
+	}
+	return result, nil
+}
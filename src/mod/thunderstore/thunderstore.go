@@ -3,6 +3,7 @@ package thunderstore
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -27,52 +28,75 @@ type Package struct {
 }
 
 type Version struct {
-	Name          string        `json:"name"`
-	FullName      string        `json:"full_name"`
-	Description   string        `json:"description"`
-	Icon          string        `json:"icon"`
-	VersionNumber string        `json:"version_number"`
-	Dependencies  []interface{} `json:"dependencies"`
-	DownloadURL   string        `json:"download_url"`
-	Downloads     int           `json:"downloads"`
-	DateCreated   time.Time     `json:"date_created"`
-	WebsiteURL    string        `json:"website_url"`
-	IsActive      bool          `json:"is_active"`
-	UUID4         string        `json:"uuid4"`
-	FileSize      int           `json:"file_size"`
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"`
+	Description   string    `json:"description"`
+	Icon          string    `json:"icon"`
+	VersionNumber string    `json:"version_number"`
+	Dependencies  []string  `json:"dependencies"`
+	DownloadURL   string    `json:"download_url"`
+	Downloads     int       `json:"downloads"`
+	DateCreated   time.Time `json:"date_created"`
+	WebsiteURL    string    `json:"website_url"`
+	IsActive      bool      `json:"is_active"`
+	UUID4         string    `json:"uuid4"`
+	FileSize      int       `json:"file_size"`
 }
 
 var thunderStoreLink = "https://northstar.thunderstore.io/api/v1/package/"
 
+// GetPackages returns the package index. The index is cached, see Configure
 func GetPackages(ctx context.Context) ([]Package, error) {
+	return index.get(ctx)
+}
+
+var errNotModified = errors.New("not modified")
+
+// fetchPackages downloads the package index. errNotModified is returned, when the index didn't change since the
+// given etag, or last modified time.
+func fetchPackages(ctx context.Context, etag string, lastModified string) ([]Package, string, string, error) {
 	client := http.Client{
-		Timeout: time.Second * 10,
+		Timeout: time.Second * 30,
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, thunderStoreLink, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
 	}
 
 	res, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed http call to get packages: %w", err)
+		return nil, "", "", fmt.Errorf("failed http call to get packages: %w", err)
 	}
 
 	defer res.Body.Close()
 
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		return nil, etag, lastModified, errNotModified
+	case http.StatusOK:
+	default:
+		return nil, "", "", fmt.Errorf("unexpected status code from thunderstore: %d", res.StatusCode)
+	}
+
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, "", "", fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	var packages []Package
 	err = json.Unmarshal(body, &packages)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshal error: %w", err)
+		return nil, "", "", fmt.Errorf("unmarshal error: %w", err)
 	}
 
-	return packages, nil
+	return packages, res.Header.Get("ETag"), res.Header.Get("Last-Modified"), nil
 }
 
 var ErrNoSuchPackage = fmt.Errorf("no such thunderstore package")
@@ -83,7 +107,10 @@ func GetPackageByName(ctx context.Context, name string) (Package, error) {
 	if err != nil {
 		return Package{}, err
 	}
+	return findPackageByName(packages, name)
+}
 
+func findPackageByName(packages []Package, name string) (Package, error) {
 	// if name contains /, then split into name and owner
 	// then check if owner matches and name matches
 	var owner string
@@ -127,9 +154,37 @@ func GetPackageByName(ctx context.Context, name string) (Package, error) {
 
 var ErrNoVersionsDetected = fmt.Errorf("no versions detected")
 
+// GetLatestPackageVersion returns the newest version, that wasn't taken down
 func GetLatestPackageVersion(pkg Package) (Version, error) {
-	if len(pkg.Versions) > 0 {
-		return pkg.Versions[0], nil
+	for _, version := range pkg.Versions {
+		if version.IsActive {
+			return version, nil
+		}
 	}
 	return Version{}, ErrNoVersionsDetected
 }
+
+var ErrNoSuchVersion = fmt.Errorf("no such package version")
+var ErrInactiveVersion = fmt.Errorf("package version is no longer available")
+var ErrDeprecatedPackage = fmt.Errorf("package is deprecated")
+
+func GetPackageVersion(pkg Package, versionNumber string) (Version, error) {
+	for _, version := range pkg.Versions {
+		if version.VersionNumber == versionNumber {
+			if !version.IsActive {
+				return Version{}, fmt.Errorf("%w: %s", ErrInactiveVersion, version.FullName)
+			}
+			return version, nil
+		}
+	}
+	return Version{}, fmt.Errorf("%w: %s %s", ErrNoSuchVersion, pkg.FullName, versionNumber)
+}
+
+// SplitVersion splits Owner/Name@1.2.3 into the package name, and the pinned version. Version is empty, when the
+// package is not pinned.
+func SplitVersion(name string) (string, string) {
+	if idx := strings.LastIndex(name, "@"); idx != -1 {
+		return name[:idx], name[idx+1:]
+	}
+	return name, ""
+}
@@ -2,23 +2,26 @@ package mod
 
 import (
 	"context"
-	"fmt"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod/thunderstore"
 )
 
-type TitanDebug struct{}
+type TitanDebug struct {
+	// Version pins the package version. Latest version is installed when empty
+	Version string
+}
+
+func (h TitanDebug) Spec(ctx context.Context) (ModSpec, error) {
+	return thunderstoreMod(ctx, "TitanDebug", h.Version)
+}
 
-func (r TitanDebug) Validate(otherMods []Mod) error {
-	for _, mod := range otherMods {
-		_, ok := mod.(*RebalancedLTS)
-		if ok {
-			return fmt.Errorf("cannot have both rebalanced, and titan debug enabled. Please explicitly enable/disable both mods")
-		}
-	}
-	return nil
+func (h TitanDebug) thunderstoreRequest() (thunderstore.Request, bool) {
+	return thunderstore.Request{Name: "TitanDebug", Version: h.Version}, true
 }
 
-func (h TitanDebug) ModParams(ctx context.Context) (string, string, string, string, bool, error) {
-	return latestThunderstoreMod(ctx, "TitanDebug")
+func (h TitanDebug) withVersion(version string) Mod {
+	h.Version = version
+	return &h
 }
 
 func (h TitanDebug) EnabledByDefault() bool {
@@ -3,6 +3,7 @@ package mod
 import (
 	"context"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/l1ghthouse/northstar-bootstrap/src/mod/thunderstore"
@@ -25,9 +26,26 @@ func latestGithubReleaseTag(ctx context.Context, repoOwner string, repoName stri
 	return "", ErrNoTagsFound
 }
 
-func cmdWgetZipBuilder(link string, zipName string) string {
+type workDirKey struct{}
+
+// WithWorkDir returns context, that makes specs download, extract, and clone mods under dir, instead of the root
+// directory, so servers sharing a host don't install mods over each other
+func WithWorkDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, workDirKey{}, dir)
+}
+
+// workPath joins elem to the work directory of the context
+func workPath(ctx context.Context, elem ...string) string {
+	dir, ok := ctx.Value(workDirKey{}).(string)
+	if !ok || dir == "" {
+		dir = "/"
+	}
+	return path.Join(append([]string{dir}, elem...)...)
+}
+
+func cmdWgetZipBuilder(link string, archive string) string {
 	builder := strings.Builder{}
-	builder.WriteString(fmt.Sprintf("wget %s -O %s.zip", link, zipName))
+	builder.WriteString(fmt.Sprintf("wget %s -O %s", link, archive))
 	builder.WriteString("\n")
 	return builder.String()
 }
@@ -39,38 +57,73 @@ func cmdUnzipBuilder(zipName string) string {
 	return builder.String()
 }
 
-func cmdUnzipBuilderWithDst(zipName string) string {
+func cmdUnzipBuilderWithDst(ctx context.Context, zipName string) string {
+	dst := workPath(ctx, zipName)
 	builder := strings.Builder{}
-	builder.WriteString(fmt.Sprintf("mkdir -p /%s", zipName))
+	builder.WriteString(fmt.Sprintf("mkdir -p %s", dst))
 	builder.WriteString("\n")
-	builder.WriteString(fmt.Sprintf("unzip %s.zip -d /%s", zipName, zipName))
+	builder.WriteString(fmt.Sprintf("unzip -o %s -d %s", workPath(ctx, zipName+".zip"), dst))
 	builder.WriteString("\n")
 	return builder.String()
 }
 
-func latestThunderstoreMod(ctx context.Context, packageName string) (string, string, string, string, bool, error) {
-	pkg, err := thunderstore.GetPackageByName(ctx, packageName)
-	if err != nil {
-		return "", "", "", "", false, fmt.Errorf("failed to get package: %w", err)
-	}
-	latestVersion, err := thunderstore.GetLatestPackageVersion(pkg)
-	if err != nil {
-		return "", "", "", "", false, fmt.Errorf("failed to get latest package version: %w", err)
+// thunderstoreMod installs given version of the package, or the latest one when version is empty
+func thunderstoreMod(ctx context.Context, packageName string, version string) (ModSpec, error) {
+	resolution, ok := resolvedThunderstorePackage(ctx, thunderstore.Request{Name: packageName, Version: version})
+	if !ok {
+		resolutions, err := thunderstore.ResolveAll(ctx, []thunderstore.Request{{Name: packageName, Version: version}})
+		if err != nil {
+			return ModSpec{}, fmt.Errorf("failed to resolve package: %w", err)
+		}
+		resolution = resolutions[0]
 	}
+	pkg := resolution.Package
+	latestVersion := resolution.Version
+	dependencies := resolution.Dependencies
 
 	builder := strings.Builder{}
-	builder.WriteString(cmdWgetZipBuilder(latestVersion.DownloadURL, pkg.Name))
-	builder.WriteString(cmdUnzipBuilderWithDst(pkg.Name))
-	builder.WriteString(fmt.Sprintf("cp -r /%s/mods/* /mods/", pkg.Name))
+	// dependencies are installed first, and are extracted under Owner.Name to not collide with the package itself
+	dependencyNames := make([]string, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		cmd, _, err := cmdZipModBuilder(ctx, dependency.Version.DownloadURL, dependency.Package.Owner+"."+dependency.Package.Name, defaultModsDir)
+		if err != nil {
+			return ModSpec{}, err
+		}
+		builder.WriteString(cmd)
+		dependencyNames = append(dependencyNames, dependency.Version.FullName)
+	}
+	cmd, checksum, err := cmdVerifiedWgetZipBuilder(ctx, latestVersion.DownloadURL, pkg.Name)
+	if err != nil {
+		return ModSpec{}, err
+	}
+	builder.WriteString(cmd)
+	builder.WriteString(cmdUnzipBuilderWithDst(ctx, pkg.Name))
+	builder.WriteString(fmt.Sprintf("cp -r %s/* /mods/", workPath(ctx, pkg.Name, defaultModsDir)))
 	builder.WriteString("\n")
 
-	requiredByClient := false
+	return ModSpec{
+		Cmd:                 builder.String(),
+		ModsDir:             workPath(ctx, pkg.Name, defaultModsDir),
+		RequiredByClient:    pkg.IsClientSide(),
+		DownloadLink:        latestVersion.DownloadURL,
+		Version:             latestVersion.VersionNumber,
+		Checksum:            checksum,
+		Dependencies:        dependencyNames,
+		ThunderstorePackage: pkg.FullName,
+	}, nil
+}
 
-	for _, category := range pkg.Categories {
-		if strings.Contains(category, "Client-side") {
-			requiredByClient = true
-		}
+// cmdZipModBuilder downloads, and verifies the zip, then copies mods from the given directory of the archive to the
+// mods directory. Checksum of the zip is returned along with the commands
+func cmdZipModBuilder(ctx context.Context, link string, zipName string, modsDir string) (string, string, error) {
+	cmd, checksum, err := cmdVerifiedWgetZipBuilder(ctx, link, zipName)
+	if err != nil {
+		return "", "", err
 	}
-
-	return builder.String(), "", latestVersion.DownloadURL, latestVersion.VersionNumber, requiredByClient, nil
+	builder := strings.Builder{}
+	builder.WriteString(cmd)
+	builder.WriteString(cmdUnzipBuilderWithDst(ctx, zipName))
+	builder.WriteString(fmt.Sprintf("cp -r %s/* /mods/", workPath(ctx, zipName, modsDir)))
+	builder.WriteString("\n")
+	return builder.String(), checksum, nil
 }
@@ -0,0 +1,54 @@
+package nsserver
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// InstalledMod records a mod installed on the server
+type InstalledMod struct {
+	Name             string   `json:"name"`
+	Version          string   `json:"version"`
+	DownloadLink     string   `json:"downloadLink,omitempty"`
+	Checksum         string   `json:"checksum,omitempty"`
+	RequiredByClient bool     `json:"requiredByClient"`
+	Dependencies     []string `json:"dependencies,omitempty"`
+	// ThunderstorePackage is Owner-Name of the package, for mods installed from thunderstore
+	ThunderstorePackage string `json:"thunderstorePackage,omitempty"`
+}
+
+// InstalledMods is stored as a json column
+type InstalledMods []InstalledMod
+
+func (m InstalledMods) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal installed mods: %w", err)
+	}
+	return string(data), nil
+}
+
+func (m *InstalledMods) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("failed to unmarshal installed mods: unsupported type %T", value)
+	}
+	return json.Unmarshal(data, m)
+}
+
+// GormDataType stores installed mods the same way as datatypes.JSONMap
+func (InstalledMods) GormDataType() string {
+	return "json"
+}
@@ -15,6 +15,7 @@ type NSServer struct {
 	Region             string            `json:"region" gorm:"not null;default:null"`
 	Pin                string            `json:"pin" gorm:"not null;default:null"`
 	RequestedBy        string            `json:"requestedBy" gorm:"not null;default:null"`
+	GuildID            string            `json:"guildID" gorm:"default:null;index"`
 	SSHPrivateKey      string            `json:"sshPrivateKey" gorm:"not null;default:null"`
 	Insecure           bool              `json:"insecure" gorm:"not null;default:false"`
 	BareMetal          bool              `json:"bareMetal" gorm:"not null;default:false"`
@@ -27,9 +28,63 @@ type NSServer struct {
 	DockerImageVersion string            `json:"dockerImageVersion" gorm:"not null;default:null"`
 	EnableCheats       bool              `json:"enableCheats" gorm:"not null;default:false"`
 	ModOptions         datatypes.JSONMap `json:"options" gorm:""`
-	TickRate           uint64            `json:"tick_rate" gorm:""`
-	CreatedAt          time.Time
-	ExtraArgs          string `json:"extraArgs" gorm:"default:null"`
+	InstalledMods      InstalledMods     `json:"installedMods" gorm:""`
+	// ModConVars are keyed by mod.ConVar
+	ModConVars datatypes.JSONMap `json:"modConVars" gorm:""`
+	TickRate   uint64            `json:"tick_rate" gorm:""`
+	CreatedAt  time.Time
+	ExtraArgs  string `json:"extraArgs" gorm:"default:null"`
+	// Playlist, Map, and Mode are validated against content available with the enabled mods
+	Playlist   string `json:"playlist" gorm:"default:null"`
+	Map        string `json:"map" gorm:"default:null"`
+	Mode       string `json:"mode" gorm:"default:null"`
+	MaxPlayers uint64 `json:"maxPlayers" gorm:""`
+	ScoreLimit uint64 `json:"scoreLimit" gorm:""`
+	// TimeLimit is in minutes
+	TimeLimit uint64 `json:"timeLimit" gorm:""`
+	// DisplayName replaces the [region]name listed in the server browser
+	DisplayName string `json:"displayName" gorm:"default:null"`
+	Description string `json:"description" gorm:"default:null"`
+	// Public servers have no password. Pin is still generated for them
+	Public bool `json:"public" gorm:"not null;default:false"`
+	// Host is the instance the server runs on. Servers created before packing run on the instance named after them
+	Host          string `json:"host" gorm:"default:null;index"`
+	ContainerName string `json:"containerName" gorm:"default:null"`
+}
+
+// DefaultContainerName is the container of servers, that don't share their instance
+const DefaultContainerName = "northstar-dedicated"
+
+// HostName returns name of the instance the server runs on
+func (p *NSServer) HostName() string {
+	if p.Host != "" {
+		return p.Host
+	}
+	return p.Name
+}
+
+// Container returns name of the server container on its host
+func (p *NSServer) Container() string {
+	if p.ContainerName != "" {
+		return p.ContainerName
+	}
+	return DefaultContainerName
+}
+
+// ListedName is the name of the server in the server browser
+func (p *NSServer) ListedName() string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return fmt.Sprintf("[%s]%s", p.Region, p.Name)
+}
+
+// Password returns the password players need to join, which is empty for public servers
+func (p *NSServer) Password() string {
+	if p.Public {
+		return ""
+	}
+	return p.Pin
 }
 
 func (p *NSServer) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,32 @@
+package override
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// Override replaces the default value of a command flag, when the flag is not passed explicitly
+type Override struct {
+	ID        uuid.UUID `json:"id,omitempty" gorm:"type:uuid;primary_key;"`
+	GuildID   string    `json:"guildID" gorm:"not null;default:'';index"`
+	Command   string    `json:"command" gorm:"not null;default:null;index"`
+	Flag      string    `json:"flag" gorm:"not null;default:null"`
+	Value     string    `json:"value" gorm:"not null"`
+	UpdatedBy string    `json:"updatedBy" gorm:"not null;default:null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (o *Override) BeforeCreate(tx *gorm.DB) (err error) {
+	if o.ID == uuid.Nil {
+		u, err := uuid.NewV4()
+		if err != nil {
+			return fmt.Errorf("failed to create uuid: %w", err)
+		}
+		o.ID = u
+	}
+	return nil
+}
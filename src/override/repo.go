@@ -0,0 +1,12 @@
+package override
+
+import (
+	"context"
+)
+
+type Repo interface {
+	GetAll(ctx context.Context) ([]*Override, error)
+	// Store creates the override, or replaces the value of an existing override for the same guild, and command flag
+	Store(ctx context.Context, o *Override) error
+	Delete(ctx context.Context, guildID string, command string, flag string) error
+}
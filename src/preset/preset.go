@@ -0,0 +1,46 @@
+package preset
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	ScopeUser  = "user"
+	ScopeGuild = "guild"
+)
+
+// Preset is a named set of create_server option values. Options are keyed by the create_server option name,
+// so a preset can hold anything that can be passed to the command, including mods.
+type Preset struct {
+	ID        uuid.UUID         `json:"id,omitempty" gorm:"type:uuid;primary_key;"`
+	Name      string            `json:"name" gorm:"not null;default:null;index"`
+	GuildID   string            `json:"guildID" gorm:"not null;default:null;index"`
+	UserID    string            `json:"userID" gorm:"not null;default:''"` // empty for guild wide presets
+	CreatedBy string            `json:"createdBy" gorm:"not null;default:null"`
+	Options   datatypes.JSONMap `json:"options" gorm:""`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (p *Preset) Scope() string {
+	if p.UserID == "" {
+		return ScopeGuild
+	}
+	return ScopeUser
+}
+
+func (p *Preset) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		u, err := uuid.NewV4()
+		if err != nil {
+			return fmt.Errorf("failed to create uuid: %w", err)
+		}
+		p.ID = u
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package preset
+
+import (
+	"context"
+)
+
+type Repo interface {
+	// Store creates the preset, or replaces the options of an existing preset with the same name and scope
+	Store(ctx context.Context, p *Preset) error
+	// GetByName returns the user preset with the given name, falling back to the guild preset
+	GetByName(ctx context.Context, guildID string, userID string, name string) (*Preset, error)
+	// List returns guild presets, and presets of the given user
+	List(ctx context.Context, guildID string, userID string) ([]*Preset, error)
+	DeleteByName(ctx context.Context, guildID string, userID string, name string) error
+}
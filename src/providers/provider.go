@@ -5,8 +5,11 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/l1ghthouse/northstar-bootstrap/src/image"
 	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+	"github.com/l1ghthouse/northstar-bootstrap/src/providers/util"
 	"github.com/l1ghthouse/northstar-bootstrap/src/providers/vultr"
+	"github.com/l1ghthouse/northstar-bootstrap/src/sshkey"
 )
 
 type Provider interface {
@@ -15,6 +18,19 @@ type Provider interface {
 	GetRunningServers(context.Context) ([]*nsserver.NSServer, error)
 	DeleteServer(context.Context, *nsserver.NSServer) error
 	ExtractServerLogs(context.Context, *nsserver.NSServer) (*bytes.Buffer, error)
+	ListRegions(context.Context) ([]string, error)
+}
+
+// WarmPool is implemented by providers, that keep idle instances to start servers faster
+type WarmPool interface {
+	WarmPoolStatus(context.Context) ([]util.PoolStatus, error)
+}
+
+// ImageBuilder is implemented by providers, that can boot servers from snapshots with the game files pre-baked
+type ImageBuilder interface {
+	// BuildImage bootstraps a builder instance in the region, snapshots it, and records the snapshot for the
+	// servers of the region. A region is only built once at a time
+	BuildImage(ctx context.Context, region string, builtBy string) (*image.Image, error)
 }
 
 type Config struct {
@@ -22,10 +38,12 @@ type Config struct {
 	Vultr vultr.Config
 }
 
-func NewProvider(cfg Config) (Provider, error) {
+// NewProvider creates the configured provider. repo is used to find hosts, that servers can be packed on, images to
+// find snapshots, that servers can boot from, and sshKeys to keep ssh keys of the provider across restarts
+func NewProvider(cfg Config, repo nsserver.Repo, images image.Repo, sshKeys sshkey.Repo) (Provider, error) {
 	switch cfg.Use {
 	case "vultr":
-		p, err := vultr.NewVultrProvider(cfg.Vultr)
+		p, err := vultr.NewVultrProvider(cfg.Vultr, repo, images, sshKeys)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create vultr provider: %w", err)
 		}
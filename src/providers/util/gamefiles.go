@@ -0,0 +1,197 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// GameFilesSourceOCI downloads the files from layers of an OCI manifest
+	GameFilesSourceOCI = "oci"
+	// GameFilesSourceHTTP downloads a tar, or zip archive of the files, like from a regional mirror
+	GameFilesSourceHTTP = "http"
+	// GameFilesSourcePath uses files already present on the host, like a mounted volume
+	GameFilesSourcePath = "path"
+	// GameFilesSourceObjectStorage downloads a tar, or zip archive of the files from S3 compatible object storage,
+	// with a pre-signed url
+	GameFilesSourceObjectStorage = "objectstorage"
+)
+
+const (
+	// GameFilesSetOptimized has the vpks optimized for dedicated servers
+	GameFilesSetOptimized = "optimized"
+	GameFilesSetFull      = "full"
+)
+
+// gameFilesSetPlaceholder is replaced with the set in archive urls, and object keys
+const gameFilesSetPlaceholder = "{set}"
+
+// downloadedGameFilesDir is where downloaded game files are stored on the host
+const downloadedGameFilesDir = "/titanfall2"
+
+var ErrInvalidGameFiles = errors.New("invalid game files config")
+
+var gameFilesPathRegexp = regexp.MustCompile(`^/[A-Za-z0-9_./-]+$`)
+
+// GameFilesConfig configures where hosts get the Titanfall 2 files from
+type GameFilesConfig struct {
+	// Source is oci, http, path, or objectstorage
+	Source string `default:"oci"`
+	// Set is optimized, or full
+	Set string `default:"optimized"`
+	// Registry, Repository, and the tag of the set locate the OCI manifest. Token is sent as bearer token, anonymous
+	// ghcr token by default
+	Registry     string `default:"https://ghcr.io"`
+	Repository   string `default:"nsres/titanfall"`
+	Token        string `default:"QQ=="`
+	OptimizedTag string `default:"2.0.11.0-dedicated-mp-vpkoptim.430d3bb"`
+	FullTag      string `default:"2.0.11.0-dedicated-mp"`
+	// URL of the archive for the http source. {set} is replaced with the set
+	URL string
+	// Path is the directory with the files on the host for the path source
+	Path          string
+	ObjectStorage ObjectStorageConfig
+}
+
+// ObjectStorageConfig locates the archive of the files in S3 compatible object storage, like vultr object storage.
+// The keys only sign the download url, and aren't sent to hosts
+type ObjectStorageConfig struct {
+	// Endpoint is the host name of the object storage, like ewr1.vultrobjects.com
+	Endpoint string
+	Bucket   string
+	// Key of the archive. {set} is replaced with the set
+	Key       string
+	AccessKey string
+	SecretKey string
+	// Region the url is signed for
+	Region string `default:"us-east-1"`
+	// ExpirySeconds is how long the url is valid after the bootstrap is rendered
+	ExpirySeconds uint `default:"21600"`
+}
+
+// GameFiles is the data model of the game files download in the startup templates. Templates have to quote the
+// values
+type GameFiles struct {
+	Source string
+	// Dir is the host directory with the files, that is mounted into the server containers
+	Dir string
+	// Manifest is the OCI manifest url, and layers are downloaded from BlobURL followed by their digest
+	Manifest string
+	BlobURL  string
+	Token    string
+	// URL of the archive for the http source. The renderer sets a pre-signed url for the objectstorage source
+	URL           string
+	objectStorage ObjectStorageConfig
+	// Archive is the file the archive is downloaded to. Its extension tells, how it is extracted
+	Archive string
+	Zip     bool
+}
+
+// newGameFiles validates the config, and resolves the files of the configured set
+func newGameFiles(cfg GameFilesConfig) (GameFiles, error) {
+	source := cfg.Source
+	if source == "" {
+		source = GameFilesSourceOCI
+	}
+	set := cfg.Set
+	if set == "" {
+		set = GameFilesSetOptimized
+	}
+	if set != GameFilesSetOptimized && set != GameFilesSetFull {
+		return GameFiles{}, fmt.Errorf("%w: unknown set %s, must be %s, or %s", ErrInvalidGameFiles, set, GameFilesSetOptimized, GameFilesSetFull)
+	}
+
+	files := GameFiles{Source: source, Dir: downloadedGameFilesDir}
+	switch source {
+	case GameFilesSourceOCI:
+		registry := strings.TrimSuffix(valueOr(cfg.Registry, "https://ghcr.io"), "/")
+		repository := valueOr(cfg.Repository, "nsres/titanfall")
+		tag := valueOr(cfg.OptimizedTag, "2.0.11.0-dedicated-mp-vpkoptim.430d3bb")
+		if set == GameFilesSetFull {
+			tag = valueOr(cfg.FullTag, "2.0.11.0-dedicated-mp")
+		}
+		if _, err := url.ParseRequestURI(registry); err != nil {
+			return GameFiles{}, fmt.Errorf("%w: invalid registry %s: %v", ErrInvalidGameFiles, registry, err)
+		}
+		files.Manifest = fmt.Sprintf("%s/v2/%s/manifests/%s", registry, repository, tag)
+		files.BlobURL = fmt.Sprintf("%s/v2/%s/blobs/", registry, repository)
+		files.Token = cfg.Token
+	case GameFilesSourceHTTP:
+		if cfg.URL == "" {
+			return GameFiles{}, fmt.Errorf("%w: url is required for the %s source", ErrInvalidGameFiles, source)
+		}
+		files.URL = strings.ReplaceAll(cfg.URL, gameFilesSetPlaceholder, set)
+		if _, err := url.ParseRequestURI(files.URL); err != nil {
+			return GameFiles{}, fmt.Errorf("%w: invalid url %s: %v", ErrInvalidGameFiles, files.URL, err)
+		}
+		files.setArchive(files.URL)
+	case GameFilesSourceObjectStorage:
+		storage := cfg.ObjectStorage
+		if storage.Endpoint == "" || storage.Bucket == "" || storage.Key == "" || storage.AccessKey == "" || storage.SecretKey == "" {
+			return GameFiles{}, fmt.Errorf("%w: endpoint, bucket, key, accesskey, and secretkey are required for the %s source", ErrInvalidGameFiles, source)
+		}
+		storage.Region = valueOr(storage.Region, "us-east-1")
+		if storage.ExpirySeconds == 0 {
+			storage.ExpirySeconds = 21600
+		}
+		if time.Duration(storage.ExpirySeconds)*time.Second > maxPresignExpiry {
+			return GameFiles{}, fmt.Errorf("%w: expiryseconds must be at most %d", ErrInvalidGameFiles, int(maxPresignExpiry.Seconds()))
+		}
+		storage.Key = strings.TrimPrefix(strings.ReplaceAll(storage.Key, gameFilesSetPlaceholder, set), "/")
+		files.objectStorage = storage
+		files.setArchive(storage.Key)
+	case GameFilesSourcePath:
+		if !gameFilesPathRegexp.MatchString(cfg.Path) {
+			return GameFiles{}, fmt.Errorf("%w: path %q must be absolute, and consist of letters, digits, _, ., -, or /", ErrInvalidGameFiles, cfg.Path)
+		}
+		files.Dir = strings.TrimSuffix(cfg.Path, "/")
+	default:
+		return GameFiles{}, fmt.Errorf("%w: unknown source %s, must be %s, %s, %s, or %s", ErrInvalidGameFiles, source,
+			GameFilesSourceOCI, GameFilesSourceHTTP, GameFilesSourcePath, GameFilesSourceObjectStorage)
+	}
+	return files, nil
+}
+
+func valueOr(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func (g *GameFiles) setArchive(name string) {
+	g.Zip = strings.HasSuffix(strings.ToLower(name), ".zip")
+	g.Archive = "/titanfall2-files.tar"
+	if g.Zip {
+		g.Archive = "/titanfall2-files.zip"
+	}
+}
+
+// ID identifies the files, so images with other files baked in aren't used
+func (g GameFiles) ID() string {
+	switch g.Source {
+	case GameFilesSourceOCI:
+		return g.Manifest
+	case GameFilesSourceHTTP:
+		return g.URL
+	case GameFilesSourceObjectStorage:
+		return fmt.Sprintf("s3://%s/%s/%s", g.objectStorage.Endpoint, g.objectStorage.Bucket, g.objectStorage.Key)
+	default:
+		return g.Source + ":" + g.Dir
+	}
+}
+
+// presigned returns the files with a url of the object, that is valid from now, for the objectstorage source
+func (g GameFiles) presigned(now time.Time) GameFiles {
+	if g.Source != GameFilesSourceObjectStorage {
+		return g
+	}
+	storage := g.objectStorage
+	g.URL = presignGetURL(storage.Endpoint, storage.Bucket+"/"+storage.Key, storage.Region, storage.AccessKey, storage.SecretKey,
+		time.Duration(storage.ExpirySeconds)*time.Second, now)
+	return g
+}
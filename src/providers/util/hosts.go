@@ -0,0 +1,121 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+)
+
+// HostPrefix is prepended to the names of instances, that can be shared by several servers
+const HostPrefix = "host-"
+
+// HostReadyMarker is created once the instance is bootstrapped, and servers can be started on it
+const HostReadyMarker = "/northstar-host-ready"
+
+// Host is an instance running one, or more servers
+type Host struct {
+	Name    string
+	Region  string
+	Servers []*nsserver.NSServer
+}
+
+// GroupByHost returns hosts of the servers, keyed by host name
+func GroupByHost(servers []*nsserver.NSServer) map[string]*Host {
+	hosts := make(map[string]*Host)
+	for _, server := range servers {
+		name := server.HostName()
+		host, ok := hosts[name]
+		if !ok {
+			host = &Host{Name: name, Region: server.Region}
+			hosts[name] = host
+		}
+		host.Servers = append(host.Servers, server)
+	}
+	return hosts
+}
+
+// FreeSlot returns the lowest slot not used by servers of the host. Slots offset ports of the server from the
+// base ports, so servers of the same host don't collide
+func (h *Host) FreeSlot(capacity int, baseGamePort int) (int, bool) {
+	used := make(map[int]bool, len(h.Servers))
+	for _, server := range h.Servers {
+		used[server.GameUDPPort-baseGamePort] = true
+	}
+	for slot := 0; slot < capacity; slot++ {
+		if !used[slot] {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+// PickHost returns the fullest host in the region, that has a free slot. Bare metal hosts, and hosts of servers
+// created before packing are not shared
+func PickHost(servers []*nsserver.NSServer, region string, capacity int, baseGamePort int) (*Host, int, bool) {
+	hosts := GroupByHost(servers)
+	names := make([]string, 0, len(hosts))
+	for name := range hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var picked *Host
+	var pickedSlot int
+	for _, name := range names {
+		host := hosts[name]
+		if host.Region != region || !isShared(host) {
+			continue
+		}
+		slot, ok := host.FreeSlot(capacity, baseGamePort)
+		if !ok {
+			continue
+		}
+		if picked == nil || len(host.Servers) > len(picked.Servers) {
+			picked = host
+			pickedSlot = slot
+		}
+	}
+	return picked, pickedSlot, picked != nil
+}
+
+func isShared(host *Host) bool {
+	for _, server := range host.Servers {
+		if server.BareMetal || server.Host == "" || server.MainIP == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// PoolStatus reports idle instances kept in a region, so servers are started faster
+type PoolStatus struct {
+	Region     string
+	Idle       int
+	Size       int
+	HourlyCost float64
+}
+
+// SlotContainerName returns the container name of the server in the slot. First slot keeps the name used before
+// packing
+func SlotContainerName(slot int) string {
+	if slot == 0 {
+		return nsserver.DefaultContainerName
+	}
+	return fmt.Sprintf("%s-%d", nsserver.DefaultContainerName, slot)
+}
+
+// ServerModsDir is the directory mods of the container are installed to
+func ServerModsDir(container string) string {
+	return fmt.Sprintf("/servers/%s/mods", container)
+}
+
+// ServerWorkDir is the directory mods of the container are downloaded, extracted, and cloned to
+func ServerWorkDir(container string) string {
+	return fmt.Sprintf("/servers/%s/src", container)
+}
+
+// RemoveServerScript stops the container, and removes its mods, leaving other servers of the host running
+func RemoveServerScript(container string) string {
+	return fmt.Sprintf("docker rm -f %s; rm -rf /servers/%s", container, container)
+}
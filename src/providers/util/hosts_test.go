@@ -0,0 +1,74 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+)
+
+const testBaseGamePort = 37015
+
+func hostedServer(name string, host string, region string, slot int) *nsserver.NSServer {
+	return &nsserver.NSServer{
+		Name:          name,
+		Region:        region,
+		Host:          host,
+		MainIP:        "192.0.2.1",
+		GameUDPPort:   testBaseGamePort + slot,
+		ContainerName: SlotContainerName(slot),
+	}
+}
+
+func TestFreeSlot(t *testing.T) {
+	host := &Host{Servers: []*nsserver.NSServer{
+		hostedServer("first", "host-a", "Frankfurt", 0),
+		hostedServer("third", "host-a", "Frankfurt", 2),
+	}}
+	if slot, ok := host.FreeSlot(3, testBaseGamePort); !ok || slot != 1 {
+		t.Errorf("expected the lowest free slot 1, got %d, %v", slot, ok)
+	}
+	if _, ok := host.FreeSlot(1, testBaseGamePort); ok {
+		t.Error("expected the host to be full")
+	}
+}
+
+func TestIsShared(t *testing.T) {
+	cases := map[string]struct {
+		server *nsserver.NSServer
+		shared bool
+	}{
+		"packed":         {server: hostedServer("packed", "host-a", "Frankfurt", 0), shared: true},
+		"before packing": {server: &nsserver.NSServer{Name: "legacy", MainIP: "192.0.2.1"}},
+		"bare metal":     {server: &nsserver.NSServer{Name: "metal", Host: "host-a", MainIP: "192.0.2.1", BareMetal: true}},
+		"without ip":     {server: &nsserver.NSServer{Name: "starting", Host: "host-a"}},
+	}
+	for name, c := range cases {
+		if shared := isShared(&Host{Servers: []*nsserver.NSServer{c.server}}); shared != c.shared {
+			t.Errorf("%s: expected shared %v, got %v", name, c.shared, shared)
+		}
+	}
+}
+
+func TestPickHost(t *testing.T) {
+	servers := []*nsserver.NSServer{
+		hostedServer("a1", "host-a", "Frankfurt", 0),
+		hostedServer("b1", "host-b", "Frankfurt", 0),
+		hostedServer("b2", "host-b", "Frankfurt", 1),
+		hostedServer("c1", "host-c", "Frankfurt", 0),
+		hostedServer("c2", "host-c", "Frankfurt", 1),
+		hostedServer("c3", "host-c", "Frankfurt", 2),
+		hostedServer("d1", "host-d", "Chicago", 0),
+		{Name: "legacy", Region: "Frankfurt", MainIP: "192.0.2.1", GameUDPPort: testBaseGamePort},
+	}
+
+	host, slot, ok := PickHost(servers, "Frankfurt", 3, testBaseGamePort)
+	if !ok || host.Name != "host-b" || slot != 2 {
+		t.Errorf("expected the fullest host with a free slot host-b:2, got %+v:%d", host, slot)
+	}
+	if _, _, ok := PickHost(servers, "Amsterdam", 3, testBaseGamePort); ok {
+		t.Error("expected no host in a region without servers")
+	}
+	if _, _, ok := PickHost(servers[7:], "Frankfurt", 3, testBaseGamePort); ok {
+		t.Error("expected servers created before packing not to be shared")
+	}
+}
@@ -0,0 +1,72 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	signatureAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat      = "20060102T150405Z"
+	amzDayFormat       = "20060102"
+	// maxPresignExpiry is the longest validity of a signature version 4 pre-signed url
+	maxPresignExpiry = 7 * 24 * time.Hour
+)
+
+// presignGetURL returns a pre-signed url, that downloads the object at objectPath of host, until it expires. It is
+// signed with signature version 4, like the aws cli presign command, so hosts download the object without the keys
+func presignGetURL(host string, objectPath string, region string, accessKey string, secretKey string, expires time.Duration, now time.Time) string {
+	now = now.UTC()
+	amzDate := now.Format(amzDateFormat)
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", now.Format(amzDayFormat), region)
+
+	segments := strings.Split(strings.TrimPrefix(objectPath, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	canonicalURI := "/" + strings.Join(segments, "/")
+
+	// query parameters are sorted by name
+	canonicalQuery := strings.Join([]string{
+		"X-Amz-Algorithm=" + signatureAlgorithm,
+		"X-Amz-Credential=" + awsEscape(accessKey+"/"+scope),
+		"X-Amz-Date=" + amzDate,
+		fmt.Sprintf("X-Amz-Expires=%d", int(expires.Seconds())),
+		"X-Amz-SignedHeaders=host",
+	}, "&")
+
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		canonicalURI,
+		canonicalQuery,
+		"host:" + host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{signatureAlgorithm, amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), now.Format(amzDayFormat))
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return fmt.Sprintf("https://%s%s?%s&X-Amz-Signature=%s", host, canonicalURI, canonicalQuery, signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape escapes everything, but the unreserved characters of RFC 3986, as signature version 4 requires
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
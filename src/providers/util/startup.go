@@ -0,0 +1,445 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"al.essio.dev/pkg/shellescape"
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod"
+	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+)
+
+// Templates are versioned, so overrides written for an older data model stop being picked up, once it changes
+const (
+	templateVersion          = "v5"
+	startupScriptTemplate    = "startup_" + templateVersion + ".sh.tmpl"
+	startupCloudInitTemplate = "cloud-init_" + templateVersion + ".yml.tmpl"
+	// startupScriptTemplate is made of the host bootstrap, and the server. Only the server is run on hosts, that
+	// are already bootstrapped, or booted from an image with the host bootstrap baked in
+	hostTemplate     = "host_" + templateVersion + ".sh.tmpl"
+	serverTemplate   = "server_" + templateVersion + ".sh.tmpl"
+	prebakedTemplate = "prebaked_" + templateVersion + ".sh.tmpl"
+)
+
+var templateNames = []string{hostTemplate, serverTemplate, startupScriptTemplate, prebakedTemplate, startupCloudInitTemplate}
+
+// versionedTemplateRegexp matches names of templates of any version, like host_v3.sh.tmpl
+var versionedTemplateRegexp = regexp.MustCompile(`^([a-z-]+)_v[0-9]+(\.[a-z]+\.tmpl)$`)
+
+// BootstrapMarker is created by the first run of the bootstrap, so the boot script, and cloud-init don't both run it
+const BootstrapMarker = "/var/lib/northstar-bootstrap"
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+const (
+	StartupFormatScript    = "script"
+	StartupFormatCloudInit = "cloud-init"
+)
+
+// StartupConfig configures how the server bootstrap is rendered
+type StartupConfig struct {
+	// Format is script for a bash script, or cloud-init for a cloud-config, that runs the same script
+	Format string `default:"script"`
+	// TemplateDir overrides the built-in templates with the files of the same name. Templates named for another
+	// version are rejected, so outdated overrides aren't silently ignored
+	TemplateDir string
+	GameFiles   GameFilesConfig
+}
+
+const (
+	PackageManagerApt = "apt"
+	PackageManagerDnf = "dnf"
+	// PackageManagerNone skips installing packages, for images with the dependencies preinstalled
+	PackageManagerNone = "none"
+)
+
+// Platform describes the images a provider boots, so the bootstrap is rendered for them
+type Platform struct {
+	// PackageManager installs the dependencies of the bootstrap
+	PackageManager string
+	// DockerPreinstalled skips installing docker with get-docker.sh
+	DockerPreinstalled bool
+	// CloudInit is true, when the images run cloud-init user data. The cloud-init format is rejected otherwise
+	CloudInit bool
+}
+
+// StartupData is the data model of the startup templates
+type StartupData struct {
+	Image        string
+	AuthPort     int
+	GamePort     int
+	MasterServer string
+	// Password is empty for public servers. Templates have to quote it, along with ServerName, and Description
+	Password string
+	Insecure bool
+	Region   string
+	// Name is the generated name of the server, and ServerName is the one listed in the server browser
+	Name        string
+	ServerName  string
+	Description string
+	// ExtraArgs are the server launch arguments. Templates have to quote them
+	ExtraArgs string
+	// ModCommands install the mods, one command block per mod
+	ModCommands []string
+	// DockerArgs mount the mods, and pass their env vars to the server container
+	DockerArgs []string
+	// GameFiles, Platform, and BootstrapMarker are set by the renderer
+	GameFiles       GameFiles
+	Platform        Platform
+	BootstrapMarker string
+	ContainerName   string
+	// ModsDir is the host directory mounted as mods of the container
+	ModsDir string
+	// WorkDir is the host directory mods of the container are downloaded to. Mods mounted over the ones shipped with
+	// the server are mounted from it
+	WorkDir         string
+	HostReadyMarker string
+}
+
+// NewStartupData generates the mods of the server, and records them in InstalledMods. serverDesc is used, unless the
+// server has its own description
+func NewStartupData(ctx context.Context, server *nsserver.NSServer, serverDesc string, insecure bool) (StartupData, error) {
+	var modsMap = make(map[string]mod.Mod)
+
+	for option, value := range server.ModOptions {
+		knownMod := false
+		for modName, generator := range mod.ByName {
+			if option == modName {
+				knownMod = true
+				if value.(bool) {
+					modsMap[modName] = generator()
+				}
+			}
+		}
+		if !knownMod {
+			customMod, err := mod.CustomMod(option)
+			if err != nil {
+				return StartupData{}, err
+			}
+			modsMap[option] = customMod
+		}
+	}
+
+	var enabledMods []string
+	for name := range modsMap {
+		enabledMods = append(enabledMods, name)
+	}
+
+	modNames, err := mod.ResolveMods(enabledMods)
+	if err != nil {
+		return StartupData{}, err
+	}
+
+	if server.Description != "" {
+		serverDesc = server.Description
+	}
+
+	data := StartupData{
+		Image:           server.DockerImageVersion,
+		AuthPort:        server.AuthTCPPort,
+		GamePort:        server.GameUDPPort,
+		MasterServer:    server.MasterServer,
+		Password:        server.Password(),
+		Insecure:        insecure,
+		Region:          server.Region,
+		Name:            server.Name,
+		ServerName:      server.ListedName(),
+		Description:     serverDesc,
+		ContainerName:   server.Container(),
+		ModsDir:         ServerModsDir(server.Container()),
+		WorkDir:         ServerWorkDir(server.Container()),
+		HostReadyMarker: HostReadyMarker,
+	}
+
+	mods := make([]mod.Mod, len(modNames))
+	for idx, name := range modNames {
+		m, ok := modsMap[name]
+		if !ok {
+			// enabled, because another mod implies it
+			m = mod.ByName[name]()
+		}
+		mods[idx] = m
+	}
+	ctx, err = mod.ResolveThunderstore(mod.WithWorkDir(ctx, data.WorkDir), mods)
+	if err != nil {
+		return StartupData{}, fmt.Errorf("error resolving thunderstore mods: %w", err)
+	}
+
+	var modLaunchArgs []string
+	files := make(map[string]string)
+	modsDirs := make(map[string]string)
+	installedMods := make(nsserver.InstalledMods, 0, len(modNames))
+	for idx, name := range modNames {
+		spec, err := mods[idx].Spec(ctx)
+		if err != nil {
+			return StartupData{}, fmt.Errorf("error generating mod: %w", err)
+		}
+		data.ModCommands = append(data.ModCommands, spec.Cmd)
+		if dockerArgs := spec.DockerArgs(); dockerArgs != "" {
+			data.DockerArgs = append(data.DockerArgs, dockerArgs)
+		}
+		if launchArgs := spec.LaunchArgs(); launchArgs != "" {
+			modLaunchArgs = append(modLaunchArgs, launchArgs)
+		}
+		for name, content := range spec.Files {
+			files[name] = content
+		}
+		modsDirs[name] = spec.ModsDir
+		installedMods = append(installedMods, nsserver.InstalledMod{
+			Name:                name,
+			Version:             spec.Version,
+			DownloadLink:        spec.DownloadLink,
+			Checksum:            spec.Checksum,
+			RequiredByClient:    spec.RequiredByClient,
+			Dependencies:        spec.Dependencies,
+			ThunderstorePackage: spec.ThunderstorePackage,
+		})
+	}
+	server.InstalledMods = installedMods
+
+	// configuration is applied after every mod is installed, so it isn't overwritten by files of other mods
+	if len(files) > 0 {
+		data.ModCommands = append(data.ModCommands, mod.FilesCmd(files))
+	}
+	if len(server.ModConVars) > 0 {
+		conVars := make(map[string]string, len(server.ModConVars))
+		for key, value := range server.ModConVars {
+			conVars[key] = fmt.Sprintf("%v", value)
+		}
+		data.ModCommands = append(data.ModCommands, mod.ConVarsCmd(conVars, modsDirs))
+	}
+
+	var extraArgs string
+
+	if server.TickRate != 0 {
+		extraArgs += fmt.Sprintf(" +cl_updaterate_mp %d +sv_updaterate_mp %d +cl_cmdrate %d +sv_minupdaterate %d +sv_maxupdaterate %d +sv_max_snapshots_multiplayer %d +base_tickinterval_mp %.5f",
+			server.TickRate, server.TickRate, server.TickRate, server.TickRate, server.TickRate, server.TickRate*15, 1/float64(server.TickRate))
+	}
+
+	if server.EnableCheats {
+		extraArgs += " +sv_cheats 1"
+	}
+
+	for _, launchArgs := range modLaunchArgs {
+		extraArgs += " " + launchArgs
+	}
+
+	// additional extra args come after the game options, so they can override them
+	if gameArgs := GameArgs(server); gameArgs != "" {
+		extraArgs += " " + gameArgs
+	}
+
+	if server.ExtraArgs != "" {
+		extraArgs += " " + server.ExtraArgs
+	}
+
+	extraArgs += " +ns_allow_spectators 1"
+	data.ExtraArgs = extraArgs
+
+	return data, nil
+}
+
+// GameArgs translates playlist, map, mode, and playlist var overrides of the server into launch arguments
+func GameArgs(server *nsserver.NSServer) string {
+	var args []string
+	if server.Playlist != "" {
+		args = append(args, "+setplaylist "+server.Playlist)
+	}
+	if server.Mode != "" {
+		args = append(args, "+mp_gamemode "+server.Mode)
+	}
+	if server.Map != "" {
+		args = append(args, "+map "+server.Map)
+	}
+
+	var overrides []string
+	if server.MaxPlayers != 0 {
+		overrides = append(overrides, fmt.Sprintf("max_players %d", server.MaxPlayers))
+	}
+	if server.ScoreLimit != 0 {
+		overrides = append(overrides, fmt.Sprintf("scorelimit %d", server.ScoreLimit))
+	}
+	if server.TimeLimit != 0 {
+		overrides = append(overrides, fmt.Sprintf("timelimit %d", server.TimeLimit))
+	}
+	if len(overrides) > 0 {
+		args = append(args, fmt.Sprintf("+setplaylistvaroverrides \"%s\"", strings.Join(overrides, " ")))
+	}
+	return strings.Join(args, " ")
+}
+
+var ErrUnknownStartupFormat = errors.New("unknown startup format")
+var ErrUnsupportedStartupFormat = errors.New("startup format is not supported by the provider")
+var ErrUnknownPackageManager = errors.New("unknown package manager")
+var ErrOutdatedTemplate = errors.New("outdated template override")
+
+// StartupRenderer renders the server bootstrap from templates
+type StartupRenderer struct {
+	format    string
+	templates *template.Template
+	gameFiles GameFiles
+	platform  Platform
+}
+
+// NewStartupRenderer creates the renderer of the bootstrap for the images of a provider
+func NewStartupRenderer(cfg StartupConfig, platform Platform) (*StartupRenderer, error) {
+	format := cfg.Format
+	if format == "" {
+		format = StartupFormatScript
+	}
+	if format != StartupFormatScript && format != StartupFormatCloudInit {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStartupFormat, format)
+	}
+	if format == StartupFormatCloudInit && !platform.CloudInit {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedStartupFormat, format)
+	}
+	switch platform.PackageManager {
+	case PackageManagerApt, PackageManagerDnf, PackageManagerNone:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPackageManager, platform.PackageManager)
+	}
+	if err := checkTemplateDir(cfg.TemplateDir); err != nil {
+		return nil, err
+	}
+	gameFiles, err := newGameFiles(cfg.GameFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := template.New("startup")
+	templates.Funcs(template.FuncMap{
+		"quote": shellescape.Quote,
+		"join":  strings.Join,
+		"indent": func(spaces int, s string) string {
+			lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+			for idx, line := range lines {
+				if line != "" {
+					lines[idx] = strings.Repeat(" ", spaces) + line
+				}
+			}
+			return strings.Join(lines, "\n")
+		},
+		// include renders a template, so its output can be piped, unlike the output of the template action
+		"include": func(name string, data interface{}) (string, error) {
+			buf := &bytes.Buffer{}
+			err := templates.ExecuteTemplate(buf, name, data)
+			return buf.String(), err
+		},
+	})
+
+	for _, name := range templateNames {
+		content, err := builtinTemplates.ReadFile("templates/" + name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read built-in template %s: %w", name, err)
+		}
+		if cfg.TemplateDir != "" {
+			override, err := os.ReadFile(filepath.Join(cfg.TemplateDir, name))
+			switch {
+			case err == nil:
+				content = override
+			case !errors.Is(err, os.ErrNotExist):
+				return nil, fmt.Errorf("failed to read template %s: %w", name, err)
+			}
+		}
+		if _, err := templates.New(name).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+	}
+
+	return &StartupRenderer{format: format, templates: templates, gameFiles: gameFiles, platform: platform}, nil
+}
+
+// checkTemplateDir rejects overrides named for another version of a template, since they would be ignored. Other
+// files are only reported
+func checkTemplateDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read template dir %s: %w", dir, err)
+	}
+	current := make(map[string]bool, len(templateNames))
+	for _, name := range templateNames {
+		current[name] = true
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || current[name] {
+			continue
+		}
+		if match := versionedTemplateRegexp.FindStringSubmatch(name); match != nil {
+			renamed := match[1] + "_" + templateVersion + match[2]
+			if current[renamed] {
+				return fmt.Errorf("%w: %s is written for another version. Update it to the data model of %s, and rename it", ErrOutdatedTemplate, name, renamed)
+			}
+		}
+		log.Printf("template dir %s: %s is not a template, and is ignored", dir, name)
+	}
+	return nil
+}
+
+// Format returns the format of the rendered bootstrap
+func (r *StartupRenderer) Format() string {
+	return r.format
+}
+
+func (r *StartupRenderer) Render(data StartupData) (string, error) {
+	name := startupScriptTemplate
+	if r.format == StartupFormatCloudInit {
+		name = startupCloudInitTemplate
+	}
+	return r.render(name, data)
+}
+
+// RenderScript renders the bash script regardless of the format, for boot scripts of images without cloud-init
+func (r *StartupRenderer) RenderScript(data StartupData) (string, error) {
+	return r.render(startupScriptTemplate, data)
+}
+
+// RenderHost renders a bash script, that only bootstraps the host, so servers can be started on it later
+func (r *StartupRenderer) RenderHost() (string, error) {
+	host, err := r.render(hostTemplate, StartupData{HostReadyMarker: HostReadyMarker})
+	if err != nil {
+		return "", err
+	}
+	return "#!/bin/bash\n" + host, nil
+}
+
+// RenderServer renders a bash script, that starts the server on a host, that is already bootstrapped
+func (r *StartupRenderer) RenderServer(data StartupData) (string, error) {
+	return r.render(serverTemplate, data)
+}
+
+// RenderPrebaked renders the bootstrap of an instance booted from an image, that has the host bootstrap baked in.
+// Only the server is started, regardless of the format
+func (r *StartupRenderer) RenderPrebaked(data StartupData) (string, error) {
+	return r.render(prebakedTemplate, data)
+}
+
+// ServerFiles identifies the game files, that hosts get
+func (r *StartupRenderer) ServerFiles() string {
+	return r.gameFiles.ID()
+}
+
+func (r *StartupRenderer) render(name string, data StartupData) (string, error) {
+	data.GameFiles = r.gameFiles.presigned(time.Now())
+	data.Platform = r.platform
+	data.BootstrapMarker = BootstrapMarker
+	buf := &bytes.Buffer{}
+	if err := r.templates.ExecuteTemplate(buf, name, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,241 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/mod"
+	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+	"gorm.io/datatypes"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// configuredMod is defined like in the configuration file, with files, and ConVars
+var configuredMod = mod.Definition{
+	Name:    "configured",
+	Source:  mod.SourceGitBranch,
+	URL:     "https://github.com/example/configured.git",
+	Branch:  "main",
+	ConVars: []string{"configured_rounds"},
+	Files: map[string]string{
+		"Example.Configured/cfg/settings.cfg": "rounds 3\nname \"it's configured\"",
+	},
+}
+
+func startupCases() map[string]*nsserver.NSServer {
+	return map[string]*nsserver.NSServer{
+		"vanilla": {
+			Name:               "vanilla-server",
+			Region:             "Frankfurt",
+			Pin:                "1234",
+			DockerImageVersion: "ghcr.io/pg9182/northstar-dedicated:1-tf2.0.11.0-ns1.10.0",
+			MasterServer:       "https://northstar.tf",
+			GameUDPPort:        37015,
+			AuthTCPPort:        8081,
+		},
+		"public": {
+			Name:               "public-server",
+			Region:             "Chicago",
+			Pin:                "1234",
+			Public:             true,
+			DisplayName:        `Bob's "best" server`,
+			Description:        "It's $HOME; `uname`",
+			DockerImageVersion: "ghcr.io/pg9182/northstar-dedicated:1-tf2.0.11.0-ns1.10.0",
+			MasterServer:       "https://northstar.tf",
+			GameUDPPort:        37015,
+			AuthTCPPort:        8081,
+			Playlist:           "ps",
+			MaxPlayers:         16,
+		},
+		"mods": {
+			Name:               "modded-server",
+			Region:             "Frankfurt",
+			Pin:                "1234",
+			DockerImageVersion: "ghcr.io/pg9182/northstar-dedicated:1-tf2.0.11.0-ns1.10.0",
+			MasterServer:       "https://northstar.tf",
+			GameUDPPort:        37015,
+			AuthTCPPort:        8081,
+			ModOptions: datatypes.JSONMap{
+				"remove_navmesh":                       true,
+				"ctf_experimental":                     true,
+				"github.com/Example/NorthstarMods@fix": true,
+			},
+		},
+		"configured": {
+			Name:               "configured-server",
+			Region:             "Frankfurt",
+			Pin:                "1234",
+			DockerImageVersion: "ghcr.io/pg9182/northstar-dedicated:1-tf2.0.11.0-ns1.10.0",
+			MasterServer:       "https://northstar.tf",
+			GameUDPPort:        37015,
+			AuthTCPPort:        8081,
+			ModOptions:         datatypes.JSONMap{"configured": true},
+			ModConVars:         datatypes.JSONMap{"configured.configured_rounds": "5; rm -rf /"},
+		},
+		"packed": {
+			Name:               "packed-server",
+			Region:             "Frankfurt",
+			Pin:                "1234",
+			DockerImageVersion: "ghcr.io/pg9182/northstar-dedicated:1-tf2.0.11.0-ns1.10.0",
+			MasterServer:       "https://northstar.tf",
+			GameUDPPort:        37016,
+			AuthTCPPort:        8082,
+			Host:               "host-example",
+			ContainerName:      SlotContainerName(1),
+			ModOptions:         datatypes.JSONMap{"github.com/Example/NorthstarMods@fix": true},
+		},
+		"packed-other": {
+			Name:               "other-packed-server",
+			Region:             "Frankfurt",
+			Pin:                "1234",
+			DockerImageVersion: "ghcr.io/pg9182/northstar-dedicated:1-tf2.0.11.0-ns1.10.0",
+			MasterServer:       "https://northstar.tf",
+			GameUDPPort:        37017,
+			AuthTCPPort:        8083,
+			Host:               "host-example",
+			ContainerName:      SlotContainerName(2),
+			ModOptions:         datatypes.JSONMap{"github.com/Other/NorthstarMods@main": true, "configured": true},
+		},
+	}
+}
+
+func TestRenderGolden(t *testing.T) {
+	if err := mod.Load([]mod.Definition{configuredMod}); err != nil {
+		t.Fatal(err)
+	}
+	platform := Platform{PackageManager: PackageManagerApt, CloudInit: true}
+	formats := map[string]string{StartupFormatScript: ".sh", StartupFormatCloudInit: ".yml"}
+
+	for name, server := range startupCases() {
+		data, err := NewStartupData(context.Background(), server, "default description", false)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for format, ext := range formats {
+			renderer, err := NewStartupRenderer(StartupConfig{Format: format}, platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rendered, err := renderer.Render(data)
+			if err != nil {
+				t.Fatalf("%s %s: %v", name, format, err)
+			}
+			assertGolden(t, filepath.Join("testdata", name+ext), rendered)
+		}
+	}
+}
+
+// TestPackedServersWorkDirs checks, that servers sharing a host download, and mount mods from their own directories
+func TestPackedServersWorkDirs(t *testing.T) {
+	if err := mod.Load([]mod.Definition{configuredMod}); err != nil {
+		t.Fatal(err)
+	}
+	cases := startupCases()
+	for _, name := range []string{"packed", "packed-other"} {
+		data, err := NewStartupData(context.Background(), cases[name], "default description", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		workDir := ServerWorkDir(cases[name].Container())
+		for _, cmd := range data.ModCommands {
+			for _, line := range strings.Split(strings.TrimSpace(cmd), "\n") {
+				downloads := strings.HasPrefix(line, "git ") || strings.HasPrefix(line, "wget ") || strings.HasPrefix(line, "unzip ")
+				if downloads && !strings.Contains(line, workDir+"/") {
+					t.Errorf("%s: command doesn't use %s\n%s", name, workDir, line)
+				}
+			}
+		}
+		for _, args := range data.DockerArgs {
+			if strings.Contains(args, "source=") && !strings.Contains(args, "source="+workDir+"/") {
+				t.Errorf("%s: mod isn't mounted from %s\n%s", name, workDir, args)
+			}
+		}
+	}
+}
+
+func TestRenderPlatform(t *testing.T) {
+	server := startupCases()["vanilla"]
+	data, err := NewStartupData(context.Background(), server, "default description", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	platforms := map[string]Platform{
+		"dnf":  {PackageManager: PackageManagerDnf},
+		"none": {PackageManager: PackageManagerNone, DockerPreinstalled: true},
+	}
+	for name, platform := range platforms {
+		renderer, err := NewStartupRenderer(StartupConfig{}, platform)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rendered, err := renderer.Render(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		assertGolden(t, filepath.Join("testdata", "platform-"+name+".sh"), rendered)
+	}
+
+	if _, err := NewStartupRenderer(StartupConfig{Format: StartupFormatCloudInit}, Platform{PackageManager: PackageManagerApt}); !errors.Is(err, ErrUnsupportedStartupFormat) {
+		t.Errorf("expected %v for cloud-init without cloud-init support, got %v", ErrUnsupportedStartupFormat, err)
+	}
+	if _, err := NewStartupRenderer(StartupConfig{}, Platform{PackageManager: "pacman"}); !errors.Is(err, ErrUnknownPackageManager) {
+		t.Errorf("expected %v, got %v", ErrUnknownPackageManager, err)
+	}
+}
+
+func TestOutdatedTemplateDir(t *testing.T) {
+	platform := Platform{PackageManager: PackageManagerApt, CloudInit: true}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "host_v3.sh.tmpl"), []byte("echo outdated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStartupRenderer(StartupConfig{TemplateDir: dir}, platform); !errors.Is(err, ErrOutdatedTemplate) {
+		t.Errorf("expected %v, got %v", ErrOutdatedTemplate, err)
+	}
+
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, hostTemplate), []byte("echo current"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("notes"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	renderer, err := NewStartupRenderer(StartupConfig{TemplateDir: dir}, platform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, err := renderer.RenderHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "#!/bin/bash\necho current" {
+		t.Errorf("override wasn't used: %q", host)
+	}
+}
+
+func assertGolden(t *testing.T, file string, actual string) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(actual), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%v. Run go test with -update to create it", err)
+	}
+	if string(expected) != actual {
+		t.Errorf("%s doesn't match the rendered output. Run go test with -update, and review the diff\n%s", file, actual)
+	}
+}
@@ -0,0 +1,79 @@
+#!/bin/bash
+#The bootstrap runs as user data, and as a boot script for images without cloud-init. Only the first run proceeds
+mkdir /var/lib/northstar-bootstrap 2>/dev/null || exit 0
+
+apt update -y
+apt install parallel jq unzip zip -y
+
+curl -fsSL https://get.docker.com -o get-docker.sh
+sh ./get-docker.sh &
+
+echo "Downloading Titanfall2 Files"
+
+export GAME_FILES_TOKEN=''
+export GAME_FILES_BLOBS=https://ghcr.io/v2/nsres/titanfall/blobs/
+curl -L https://ghcr.io/v2/nsres/titanfall/manifests/2.0.11.0-dedicated-mp-vpkoptim.430d3bb -s -H "Accept: application/vnd.oci.image.manifest.v1+json" ${GAME_FILES_TOKEN:+-H "Authorization: Bearer $GAME_FILES_TOKEN"} | jq -r '.layers[]|[.digest, .annotations."org.opencontainers.image.title"] | @tsv' |
+{
+  paths=()
+  uri=()
+  while read -r line; do
+    while IFS=$'\t' read -r digest path; do
+      path="/titanfall2/$path"
+      folder=${path%/*}
+      mkdir -p "$folder"
+      touch "$path"
+      paths+=("$path")
+      uri+=("$GAME_FILES_BLOBS$digest")
+    done <<< "$line" ;
+  done
+  parallel --link --jobs 8 'wget -O {1} {2} ${GAME_FILES_TOKEN:+--header="Authorization: Bearer $GAME_FILES_TOKEN"} -nv' ::: "${paths[@]}" ::: "${uri[@]}"
+}
+
+#Wait for docker to finish downloading
+wait
+
+docker ps -a
+
+#Some random sleep
+sleep 5
+
+touch /northstar-host-ready
+
+export IMAGE=ghcr.io/pg9182/northstar-dedicated:1-tf2.0.11.0-ns1.10.0
+export NS_AUTH_PORT="8083"
+export NS_PORT="37017"
+export NS_MASTERSERVER_URL=https://northstar.tf
+export NS_SERVER_PASSWORD=1234
+export NS_INSECURE="0"
+export NS_SERVER_REGION=Frankfurt
+export NS_NAME=other-packed-server
+export NS_SERVER_NAME='[Frankfurt]other-packed-server'
+export NS_SERVER_DESC='default description'
+export NS_EXTRA_ARGUMENTS=' +ns_allow_spectators 1'
+
+#Servers can be added, while the host is still being bootstrapped
+until [ -f /northstar-host-ready ]; do sleep 5; done
+
+docker pull $IMAGE
+
+#Mods are downloaded to the work directory of the server, installed to /mods, and moved to the directory of the server, one server at a time
+exec 9>/var/lock/northstar-mods.lock
+flock 9
+rm -rf /mods /servers/northstar-dedicated-2/mods /servers/northstar-dedicated-2/src
+mkdir -p /mods /servers/northstar-dedicated-2/src
+
+git clone --depth 1 -b main https://github.com/example/configured.git /servers/northstar-dedicated-2/src/configured
+cp -r /servers/northstar-dedicated-2/src/configured/mods/* /mods/
+
+git clone --depth 1 -b main https://github.com/Other/NorthstarMods.git /servers/northstar-dedicated-2/src/Other.NorthstarMods.main
+
+mkdir -p /mods/Example.Configured/cfg
+printf '%s' 'rounds 3
+name "it'"'"'s configured"' > /mods/Example.Configured/cfg/settings.cfg
+
+mkdir -p "$(dirname /servers/northstar-dedicated-2/mods)"
+mv /mods /servers/northstar-dedicated-2/mods
+flock -u 9
+
+docker run -d --pull always --restart always --log-driver json-file --log-opt max-size=200m --publish $NS_AUTH_PORT:$NS_AUTH_PORT/tcp --publish $NS_PORT:$NS_PORT/udp --mount "type=bind,source=/titanfall2,target=/mnt/titanfall,readonly" --mount "type=bind,source=/servers/northstar-dedicated-2/mods,target=/mnt/mods,readonly" --mount "type=bind,source=/servers/northstar-dedicated-2/src/Other.NorthstarMods.main/Northstar.Client,target=/usr/lib/northstar/R2Northstar/mods/Northstar.Client,readonly" --mount "type=bind,source=/servers/northstar-dedicated-2/src/Other.NorthstarMods.main/Northstar.Custom,target=/usr/lib/northstar/R2Northstar/mods/Northstar.Custom,readonly" --mount "type=bind,source=/servers/northstar-dedicated-2/src/Other.NorthstarMods.main/Northstar.CustomServers,target=/usr/lib/northstar/R2Northstar/mods/Northstar.CustomServers,readonly" --env NS_SERVER_NAME --env NS_MASTERSERVER_URL --env NS_SERVER_DESC --env NS_EXTRA_ARGUMENTS --env NS_AUTH_PORT --env NS_PORT --env NS_SERVER_PASSWORD --env NS_INSECURE --name "northstar-dedicated-2" $IMAGE
+
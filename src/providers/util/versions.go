@@ -0,0 +1,459 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VersionsConfig configures the registry, that Northstar versions are discovered from
+type VersionsConfig struct {
+	// Registry is the base url of the OCI registry. A local registry can stand in for it
+	Registry   string `default:"https://ghcr.io"`
+	Repository string `default:"pg9182/northstar-dedicated"`
+	// RefreshSeconds is how often tags are listed
+	RefreshSeconds uint `default:"3600"`
+}
+
+const defaultVersionsRefresh = time.Hour
+
+// tagsPageSize is the number of tags requested per page of the tags list
+const tagsPageSize = 1000
+
+// maxTagsPages stops following the pagination of registries, that keep returning the next page
+const maxTagsPages = 20
+
+// northstarTagRegexp matches tags like 1-tf2.0.11.0-ns1.28.1, where the first number is the revision of the image,
+// and the Northstar version can have a pre-release suffix, like 1.29.0-rc1
+var northstarTagRegexp = regexp.MustCompile(`^(\d+)-tf(\d+(?:\.\d+)*)-ns(\d+(?:\.\d+)*)(?:-([0-9A-Za-z.-]+))?$`)
+
+type DockerVersion struct {
+	// Name is the Northstar version
+	Name        string
+	IsLatest    bool
+	DockerImage string
+	Prerelease  bool
+}
+
+// northstarTag is a parsed tag of the northstar dedicated image
+type northstarTag struct {
+	tag        string
+	revision   int
+	titanfall  []int
+	northstar  []int
+	prerelease string
+}
+
+func parseNorthstarTag(tag string) (northstarTag, bool) {
+	match := northstarTagRegexp.FindStringSubmatch(tag)
+	if match == nil {
+		return northstarTag{}, false
+	}
+	revision, err := strconv.Atoi(match[1])
+	if err != nil {
+		return northstarTag{}, false
+	}
+	titanfall, ok := parseVersionNumbers(match[2])
+	if !ok {
+		return northstarTag{}, false
+	}
+	northstar, ok := parseVersionNumbers(match[3])
+	if !ok {
+		return northstarTag{}, false
+	}
+	return northstarTag{tag: tag, revision: revision, titanfall: titanfall, northstar: northstar, prerelease: match[4]}, true
+}
+
+func parseVersionNumbers(version string) ([]int, bool) {
+	parts := strings.Split(version, ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		numbers[i] = n
+	}
+	return numbers, true
+}
+
+func compareVersionNumbers(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// prereleasePartRegexp splits pre-releases into runs of digits, and of other characters, like rc, 10
+var prereleasePartRegexp = regexp.MustCompile(`\d+|\D+`)
+
+// comparePrereleases compares runs of digits numerically, so rc10 is newer than rc9, and other runs alphabetically
+func comparePrereleases(a, b string) int {
+	x := prereleasePartRegexp.FindAllString(a, -1)
+	y := prereleasePartRegexp.FindAllString(b, -1)
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] == y[i] {
+			continue
+		}
+		m, errX := strconv.Atoi(x[i])
+		n, errY := strconv.Atoi(y[i])
+		if errX == nil && errY == nil && m != n {
+			if m < n {
+				return -1
+			}
+			return 1
+		}
+		if x[i] < y[i] {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case len(x) < len(y):
+		return -1
+	case len(x) > len(y):
+		return 1
+	}
+	return 0
+}
+
+// name returns the Northstar version of the tag, like 1.28.1, or 1.29.0-rc1
+func (t northstarTag) name() string {
+	parts := make([]string, len(t.northstar))
+	for i, n := range t.northstar {
+		parts[i] = strconv.Itoa(n)
+	}
+	name := strings.Join(parts, ".")
+	if t.prerelease != "" {
+		name += "-" + t.prerelease
+	}
+	return name
+}
+
+// newer reports if the tag is newer than the other one. Stable releases are newer than pre-releases of the same
+// Northstar version, and newer Titanfall, or image revisions win between tags of the same Northstar version
+func (t northstarTag) newer(other northstarTag) bool {
+	if c := compareVersionNumbers(t.northstar, other.northstar); c != 0 {
+		return c > 0
+	}
+	if (t.prerelease == "") != (other.prerelease == "") {
+		return t.prerelease == ""
+	}
+	if c := comparePrereleases(t.prerelease, other.prerelease); c != 0 {
+		return c > 0
+	}
+	if c := compareVersionNumbers(t.titanfall, other.titanfall); c != 0 {
+		return c > 0
+	}
+	return t.revision > other.revision
+}
+
+// catalogueFromTags returns versions newest first, with one image per Northstar version. Newest stable version is
+// marked as latest
+func catalogueFromTags(image string, tags []string) []DockerVersion {
+	byName := make(map[string]northstarTag)
+	for _, tag := range tags {
+		parsed, ok := parseNorthstarTag(tag)
+		if !ok {
+			continue
+		}
+		if existing, ok := byName[parsed.name()]; !ok || parsed.newer(existing) {
+			byName[parsed.name()] = parsed
+		}
+	}
+
+	parsed := make([]northstarTag, 0, len(byName))
+	for _, tag := range byName {
+		parsed = append(parsed, tag)
+	}
+	sort.Slice(parsed, func(i, j int) bool {
+		return parsed[i].newer(parsed[j])
+	})
+
+	versions := make([]DockerVersion, 0, len(parsed))
+	hasLatest := false
+	for _, tag := range parsed {
+		v := DockerVersion{
+			Name:        tag.name(),
+			DockerImage: image + ":" + tag.tag,
+			Prerelease:  tag.prerelease != "",
+		}
+		if !hasLatest && !v.Prerelease {
+			v.IsLatest = true
+			hasLatest = true
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+const NorthstarDedicatedRepo = "ghcr.io/pg9182/"
+
+var DockerTagRegexp = regexp.MustCompile("^(northstar-dedicated|northstar-dedicated-ci|northstar-dedicated-dev):([a-zA-Z0-9_.-]{1,128})$")
+
+// fallbackNorthstarVersions are used until the registry is reached
+var fallbackNorthstarVersions = []DockerVersion{
+	{
+		Name:        "1.28.1",
+		IsLatest:    true,
+		DockerImage: NorthstarDedicatedRepo + "northstar-dedicated:1-tf2.0.11.0-ns1.28.1",
+	},
+}
+
+type versionCatalogue struct {
+	lock     *sync.RWMutex
+	config   VersionsConfig
+	versions []DockerVersion
+}
+
+var catalogue = &versionCatalogue{
+	lock:     &sync.RWMutex{},
+	config:   VersionsConfig{Registry: "https://ghcr.io", Repository: "pg9182/northstar-dedicated"},
+	versions: fallbackNorthstarVersions,
+}
+
+// ConfigureVersions sets the registry, that versions are discovered from. It should be called before the versions
+// are refreshed
+func ConfigureVersions(c VersionsConfig) {
+	catalogue.lock.Lock()
+	defer catalogue.lock.Unlock()
+	catalogue.config = c
+}
+
+// NorthstarVersions returns known versions, newest first
+func NorthstarVersions() []DockerVersion {
+	catalogue.lock.RLock()
+	defer catalogue.lock.RUnlock()
+	return append([]DockerVersion(nil), catalogue.versions...)
+}
+
+func LatestStableDockerNorthstar() (string, string) {
+	for _, v := range NorthstarVersions() {
+		if v.IsLatest {
+			return v.Name, v.DockerImage
+		}
+	}
+	return "", ""
+}
+
+// RefreshNorthstarVersions lists tags of the registry, and reports if the versions changed. Versions are kept, when
+// the registry has no stable version
+func RefreshNorthstarVersions(ctx context.Context) (bool, error) {
+	catalogue.lock.RLock()
+	cfg := catalogue.config
+	catalogue.lock.RUnlock()
+
+	tags, err := listTags(ctx, cfg.Registry, cfg.Repository)
+	if err != nil {
+		return false, err
+	}
+	registryURL, err := url.Parse(cfg.Registry)
+	if err != nil {
+		return false, fmt.Errorf("invalid registry url %s: %w", cfg.Registry, err)
+	}
+	versions := catalogueFromTags(registryURL.Host+"/"+cfg.Repository, tags)
+	hasLatest := false
+	for _, v := range versions {
+		hasLatest = hasLatest || v.IsLatest
+	}
+	if !hasLatest {
+		return false, fmt.Errorf("no stable version found in %d tags of %s", len(tags), cfg.Repository)
+	}
+
+	catalogue.lock.Lock()
+	defer catalogue.lock.Unlock()
+	if equalVersions(catalogue.versions, versions) {
+		return false, nil
+	}
+	catalogue.versions = versions
+	return true, nil
+}
+
+func equalVersions(a, b []DockerVersion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WatchNorthstarVersions refreshes the versions periodically, and calls onChange, when they change
+func WatchNorthstarVersions(ctx context.Context, onChange func()) {
+	catalogue.lock.RLock()
+	interval := time.Duration(catalogue.config.RefreshSeconds) * time.Second
+	catalogue.lock.RUnlock()
+	if interval == 0 {
+		interval = defaultVersionsRefresh
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		changed, err := RefreshNorthstarVersions(ctx)
+		switch {
+		case err != nil:
+			log.Printf("unable to refresh northstar versions: %v", err)
+		case changed:
+			_, latest := LatestStableDockerNorthstar()
+			log.Printf("northstar versions changed, latest is %s", latest)
+			onChange()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type tagsList struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+var linkNextRegexp = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+// listTags lists tags of the repository with the OCI distribution tags api, following the pagination
+func listTags(ctx context.Context, registry string, repository string) ([]string, error) {
+	client := &http.Client{
+		Timeout: time.Second * 30,
+	}
+	base, err := url.Parse(registry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry url %s: %w", registry, err)
+	}
+	next, err := base.Parse(fmt.Sprintf("/v2/%s/tags/list?n=%d", repository, tagsPageSize))
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository %s: %w", repository, err)
+	}
+
+	var token string
+	var tags []string
+	for page := 0; next != nil && page < maxTagsPages; page++ {
+		res, err := getRegistry(ctx, client, next.String(), token)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == http.StatusUnauthorized && token == "" {
+			challenge := res.Header.Get("WWW-Authenticate")
+			res.Body.Close()
+			token, err = registryToken(ctx, client, challenge)
+			if err != nil {
+				return nil, err
+			}
+			res, err = getRegistry(ctx, client, next.String(), token)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code from registry: %d", res.StatusCode)
+		}
+		var list tagsList
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, fmt.Errorf("unmarshal error: %w", err)
+		}
+		tags = append(tags, list.Tags...)
+
+		next = nil
+		if match := linkNextRegexp.FindStringSubmatch(res.Header.Get("Link")); match != nil {
+			next, err = base.Parse(match[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid next page link %s: %w", match[1], err)
+			}
+		}
+	}
+	return tags, nil
+}
+
+func getRegistry(ctx context.Context, client *http.Client, target string, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed http call to list tags: %w", err)
+	}
+	return res, nil
+}
+
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// registryToken requests an anonymous pull token from the realm of the Bearer challenge
+func registryToken(ctx context.Context, client *http.Client, challenge string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("unsupported registry authentication: %s", challenge)
+	}
+	params := make(map[string]string)
+	for _, match := range challengeParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid registry authentication realm: %s", challenge)
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	res, err := getRegistry(ctx, client, realm.String(), "")
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code from registry token endpoint: %d", res.StatusCode)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("unmarshal error: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return token.Token, nil
+}
@@ -0,0 +1,138 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseNorthstarTag(t *testing.T) {
+	cases := map[string]struct {
+		ok         bool
+		name       string
+		revision   int
+		prerelease string
+	}{
+		"1-tf2.0.11.0-ns1.28.1":      {ok: true, name: "1.28.1", revision: 1},
+		"2-tf2.0.11.0-ns1.29.0-rc10": {ok: true, name: "1.29.0-rc10", revision: 2, prerelease: "rc10"},
+		"1-tf2.0.11.0-ns1.29.0-rc.1": {ok: true, name: "1.29.0-rc.1", revision: 1, prerelease: "rc.1"},
+		"latest":                     {},
+		"1-tf2.0.11.0":               {},
+		"1-tf2.0.11.0-ns1.x.1":       {},
+		"sha256-abc.sig":             {},
+	}
+	for tag, expected := range cases {
+		parsed, ok := parseNorthstarTag(tag)
+		if ok != expected.ok {
+			t.Errorf("%s: expected ok %v, got %v", tag, expected.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if parsed.name() != expected.name || parsed.revision != expected.revision || parsed.prerelease != expected.prerelease {
+			t.Errorf("%s: unexpected %+v", tag, parsed)
+		}
+	}
+}
+
+func TestCatalogueFromTags(t *testing.T) {
+	tags := []string{
+		"latest",
+		"1-tf2.0.11.0-ns1.28.1",
+		"2-tf2.0.11.0-ns1.28.1",
+		"1-tf2.0.11.0-ns1.29.0-rc9",
+		"1-tf2.0.11.0-ns1.29.0-rc10",
+		"1-tf2.0.11.0-ns1.28.2-rc1",
+		"1-tf2.0.11.0-ns1.9.0",
+	}
+	versions := catalogueFromTags("ghcr.io/pg9182/northstar-dedicated", tags)
+	expected := []DockerVersion{
+		{Name: "1.29.0-rc10", DockerImage: "ghcr.io/pg9182/northstar-dedicated:1-tf2.0.11.0-ns1.29.0-rc10", Prerelease: true},
+		{Name: "1.29.0-rc9", DockerImage: "ghcr.io/pg9182/northstar-dedicated:1-tf2.0.11.0-ns1.29.0-rc9", Prerelease: true},
+		{Name: "1.28.2-rc1", DockerImage: "ghcr.io/pg9182/northstar-dedicated:1-tf2.0.11.0-ns1.28.2-rc1", Prerelease: true},
+		{Name: "1.28.1", IsLatest: true, DockerImage: "ghcr.io/pg9182/northstar-dedicated:2-tf2.0.11.0-ns1.28.1"},
+		{Name: "1.9.0", DockerImage: "ghcr.io/pg9182/northstar-dedicated:1-tf2.0.11.0-ns1.9.0"},
+	}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Errorf("unexpected catalogue\n%+v\nexpected\n%+v", versions, expected)
+	}
+}
+
+func TestComparePrereleases(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"rc10", "rc9", 1},
+		{"rc1", "rc1", 0},
+		{"beta2", "rc1", -1},
+		{"rc.2", "rc.10", -1},
+		{"rc1", "rc1.1", -1},
+	}
+	for _, c := range cases {
+		if actual := comparePrereleases(c.a, c.b); actual != c.expected {
+			t.Errorf("comparePrereleases(%s, %s) = %d, expected %d", c.a, c.b, actual, c.expected)
+		}
+	}
+}
+
+func TestListTags(t *testing.T) {
+	const token = "anonymous-token"
+	var tokenRequests int
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		if r.URL.Query().Get("scope") != "repository:pg9182/northstar-dedicated:pull" || r.URL.Query().Get("service") != "registry.test" {
+			http.Error(w, "unexpected scope", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"token":%q}`, token)
+	})
+	mux.HandleFunc("/v2/pg9182/northstar-dedicated/tags/list", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry.test",scope="repository:pg9182/northstar-dedicated:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Query().Get("last") {
+		case "":
+			w.Header().Set("Link", `</v2/pg9182/northstar-dedicated/tags/list?n=2&last=b>; rel="next"`)
+			fmt.Fprint(w, `{"name":"pg9182/northstar-dedicated","tags":["a","b"]}`)
+		case "b":
+			w.Header().Set("Link", `</v2/pg9182/northstar-dedicated/tags/list?n=2&last=d>; rel="next"`)
+			fmt.Fprint(w, `{"name":"pg9182/northstar-dedicated","tags":["c","d"]}`)
+		default:
+			fmt.Fprint(w, `{"name":"pg9182/northstar-dedicated","tags":["e"]}`)
+		}
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	tags, err := listTags(context.Background(), server.URL, "pg9182/northstar-dedicated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []string{"a", "b", "c", "d", "e"}) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("expected the token to be requested once, got %d", tokenRequests)
+	}
+}
+
+func TestListTagsUnsupportedAuthentication(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	if _, err := listTags(context.Background(), server.URL, "pg9182/northstar-dedicated"); err == nil {
+		t.Error("expected basic authentication to be rejected")
+	}
+}
@@ -0,0 +1,294 @@
+package vultr
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/image"
+	"github.com/l1ghthouse/northstar-bootstrap/src/providers/util"
+	"github.com/vultr/govultr/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+const builderLabelPrefix = "builder-"
+const snapshotCompleteStatus = "complete"
+
+const (
+	builderPollInterval   = 30 * time.Second
+	builderBootstrapLimit = 30 * time.Minute
+	snapshotLimit         = time.Hour
+)
+
+var errTimedOutToBootstrapBuilder = errors.New("timed out to bootstrap the builder instance")
+var errTimedOutToSnapshot = errors.New("timed out to create the snapshot")
+var errImageBuildInProgress = errors.New("an image is already being built")
+
+// prepareSnapshotScript removes the builder ssh key, and makes cloud-init run again on instances booted from the
+// snapshot, so they get their own ssh key, and user data
+const prepareSnapshotScript = "rm -f /root/.ssh/authorized_keys && cloud-init clean --logs && sync"
+
+// BuildImage bootstraps a builder instance in the region, and snapshots it. The snapshot replaces the previous one
+// of the region, once it is complete
+func (v Vultr) BuildImage(ctx context.Context, city string, builtBy string) (*image.Image, error) {
+	if v.images == nil {
+		return nil, fmt.Errorf("images are not supported without an image repository")
+	}
+	c := newVultrClient(ctx, v.key)
+	region, err := c.getVultrRegionByCity(ctx, city)
+	if err != nil {
+		return nil, err
+	}
+	// regions are matched by a part of the city, so builds are locked by the region they resolve to
+	if !v.startBuild(region.ID) {
+		return nil, fmt.Errorf("%w in %s", errImageBuildInProgress, region.City)
+	}
+	defer v.finishBuild(region.ID)
+
+	label := builderLabelPrefix + util.CreateFunnyName()
+	tags := []string{v.Tags[0] + "-builder"}
+	defer func() {
+		if err := c.deleteNorthstarInstance(ctx, label, tags); err != nil {
+			log.Printf("unable to delete builder instance %s: %v", label, err)
+		}
+	}()
+
+	mainIP, privateKey, err := c.createBuilderInstance(ctx, label, region.ID, tags, v.startup)
+	if err != nil {
+		return nil, err
+	}
+	if err := waitForBootstrap(ctx, mainIP, privateKey); err != nil {
+		return nil, err
+	}
+
+	instance, err := c.getVultrInstanceByName(ctx, label, tags)
+	if err != nil {
+		return nil, err
+	}
+	snapshotID, err := c.createSnapshot(ctx, instance.ID, fmt.Sprintf("northstar %s %s", region.City, time.Now().Format("2006-01-02")))
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := v.regionImage(ctx, region.City)
+	if err != nil {
+		return nil, err
+	}
+	built := &image.Image{
+		Region:      region.City,
+		SnapshotID:  snapshotID,
+		ServerFiles: v.startup.ServerFiles(),
+		BuiltBy:     builtBy,
+	}
+	if err := v.images.Store(ctx, built); err != nil {
+		return nil, fmt.Errorf("unable to record image: %w", err)
+	}
+	if previous != nil && previous.SnapshotID != snapshotID {
+		if err := c.client.Snapshot.Delete(ctx, previous.SnapshotID); err != nil {
+			log.Printf("unable to delete previous snapshot of %s: %v", region.City, err)
+		}
+	}
+	return built, nil
+}
+
+// regionImage returns the image of the region, or nil when the region has none
+func (v Vultr) regionImage(ctx context.Context, city string) (*image.Image, error) {
+	if v.images == nil {
+		return nil, nil
+	}
+	images, err := v.images.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list images: %w", err)
+	}
+	for _, i := range images {
+		if i.Region == city {
+			return i, nil
+		}
+	}
+	return nil, nil
+}
+
+// snapshotFor returns the snapshot, that servers of the region can boot from. Snapshots with other game files than
+// the current ones are not used, and empty string is returned
+func (v Vultr) snapshotFor(ctx context.Context, city string) string {
+	i, err := v.regionImage(ctx, city)
+	if err != nil {
+		log.Printf("unable to find image of %s, booting without it: %v", city, err)
+		return ""
+	}
+	if i == nil || i.ServerFiles != v.startup.ServerFiles() {
+		return ""
+	}
+	return i.SnapshotID
+}
+
+// createBuilderInstance creates an instance, that only bootstraps the host, and waits for its ip
+func (v *vultrClient) createBuilderInstance(ctx context.Context, label string, regionID string, tags []string, startup *util.StartupRenderer) (string, string, error) {
+	script, err := startup.RenderHost()
+	if err != nil {
+		return "", "", err
+	}
+	key, err := util.GeneratePrivateKey(1024)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to generate ssh key: %w", err)
+	}
+	publicKey, err := util.GeneratePublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to generate ssh public key: %w", err)
+	}
+	sshKey, err := v.client.SSHKey.Create(ctx, &govultr.SSHKeyReq{Name: label, SSHKey: string(publicKey)})
+	if err != nil {
+		return "", "", fmt.Errorf("unable to create ssh key: %w", err)
+	}
+	osID, err := v.getVultrOsID(ctx, "Ubuntu 20.04 LTS x64")
+	if err != nil {
+		return "", "", err
+	}
+	_, err = v.createInstance(ctx, govultr.InstanceCreateReq{
+		Region:   regionID,
+		Label:    label,
+		OsID:     osID,
+		UserData: base64.StdEncoding.EncodeToString([]byte(script)),
+		Tags:     tags,
+		SSHKeys:  []string{sshKey.ID},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	ticker := time.NewTicker(builderPollInterval)
+	defer ticker.Stop()
+	maxWait := time.After(5 * time.Minute)
+	for {
+		select {
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		case <-maxWait:
+			return "", "", errTimedOutToReceivePublicIP
+		case <-ticker.C:
+			instance, err := v.getVultrInstanceByName(ctx, label, tags)
+			if err != nil {
+				if strings.Contains(err.Error(), "no instance found for") {
+					continue
+				}
+				return "", "", err
+			}
+			ip := net.ParseIP(instance.MainIP)
+			if ip == nil || ip.IsUnspecified() {
+				continue
+			}
+			return ip.String(), string(util.EncodePrivateKeyToPEM(key)), nil
+		}
+	}
+}
+
+// waitForBootstrap waits for the host ready marker of the builder, and prepares the builder to be snapshotted
+func waitForBootstrap(ctx context.Context, mainIP string, sshPrivateKey string) error {
+	ticker := time.NewTicker(builderPollInterval)
+	defer ticker.Stop()
+	maxWait := time.After(builderBootstrapLimit)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-maxWait:
+			return errTimedOutToBootstrapBuilder
+		case <-ticker.C:
+			ready, err := runOnBuilder(mainIP, sshPrivateKey, fmt.Sprintf("test -f %s", util.HostReadyMarker))
+			if err != nil || !ready {
+				continue
+			}
+			prepared, err := runOnBuilder(mainIP, sshPrivateKey, prepareSnapshotScript)
+			if err != nil {
+				return fmt.Errorf("unable to prepare the builder to be snapshotted: %w", err)
+			}
+			if !prepared {
+				return fmt.Errorf("unable to prepare the builder to be snapshotted")
+			}
+			return nil
+		}
+	}
+}
+
+// runOnBuilder runs the command, and reports if it exited successfully. Error is returned, when the builder can't be
+// reached
+func runOnBuilder(mainIP string, sshPrivateKey string, cmd string) (bool, error) {
+	sshClient, err := generateSSHClient(mainIP, sshPrivateKey)
+	if err != nil {
+		return false, err
+	}
+	defer func(sshClient *ssh.Client) {
+		err := sshClient.Close()
+		if err != nil {
+			log.Printf("failed to close ssh client: %v", err)
+		}
+	}(sshClient)
+
+	sshSession, err := sshClient.NewSession()
+	if err != nil {
+		return false, fmt.Errorf("unable to create ssh session: %w", err)
+	}
+	err = sshSession.Run(cmd)
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// createSnapshot snapshots the instance, and waits for the snapshot to complete
+func (v *vultrClient) createSnapshot(ctx context.Context, instanceID string, description string) (string, error) {
+	snapshot, err := v.client.Snapshot.Create(ctx, &govultr.SnapshotReq{InstanceID: instanceID, Description: description})
+	if err != nil {
+		return "", fmt.Errorf("unable to create snapshot: %w", err)
+	}
+
+	ticker := time.NewTicker(builderPollInterval)
+	defer ticker.Stop()
+	maxWait := time.After(snapshotLimit)
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-maxWait:
+			if err := v.client.Snapshot.Delete(ctx, snapshot.ID); err != nil {
+				log.Printf("unable to delete incomplete snapshot %s: %v", snapshot.ID, err)
+			}
+			return "", errTimedOutToSnapshot
+		case <-ticker.C:
+			s, err := v.client.Snapshot.Get(ctx, snapshot.ID)
+			if err != nil {
+				log.Printf("unable to get snapshot %s: %v", snapshot.ID, err)
+				continue
+			}
+			if s.Status == snapshotCompleteStatus {
+				return s.ID, nil
+			}
+		}
+	}
+}
+
+// startBuild reports false, when the region is already being built
+func (v Vultr) startBuild(regionID string) bool {
+	v.buildLock.Lock()
+	defer v.buildLock.Unlock()
+	if v.builds[regionID] {
+		return false
+	}
+	v.builds[regionID] = true
+	return true
+}
+
+func (v Vultr) finishBuild(regionID string) {
+	v.buildLock.Lock()
+	defer v.buildLock.Unlock()
+	delete(v.builds, regionID)
+}
@@ -0,0 +1,331 @@
+package vultr
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
+	"github.com/l1ghthouse/northstar-bootstrap/src/providers/util"
+	"github.com/l1ghthouse/northstar-bootstrap/src/sshkey"
+	"github.com/vultr/govultr/v2"
+)
+
+// PoolConfig keeps idle instances bootstrapped in the region, so servers only have to start their container
+type PoolConfig struct {
+	// Region is matched against region cities, like the create_server region option
+	Region string `required:"true"`
+	Size   uint   `default:"1"`
+}
+
+const poolLabelPrefix = "pool-"
+const poolRefreshInterval = 2 * time.Minute
+
+// vultr bills hourly, capped at 672 hours a month
+const billedHoursPerMonth = 672
+
+// warmPool keeps idle instances, that are tagged with a separate tag, so they aren't listed as servers.
+// The ssh key of the pool is stored, so instances left by a previous run are reused after a restart.
+type warmPool struct {
+	key      string
+	tag      string
+	sizes    map[string]int
+	renderer *util.StartupRenderer
+	lock     *sync.Mutex
+	// owned are the ids of the instances created with the current ssh key
+	owned map[string]bool
+	// sshKeys keep the ssh key of the pool across restarts
+	sshKeys     sshkey.Repo
+	privateKey  string
+	sshKeyID    string
+	hourlyCosts map[string]float64
+	refreshC    chan struct{}
+	// snapshotFor returns the snapshot, that instances of the region boot from, or empty string
+	snapshotFor func(ctx context.Context, city string) string
+}
+
+func newWarmPool(cfg Config, renderer *util.StartupRenderer, sshKeys sshkey.Repo) *warmPool {
+	if len(cfg.Pool) == 0 {
+		return nil
+	}
+	sizes := make(map[string]int, len(cfg.Pool))
+	for _, p := range cfg.Pool {
+		sizes[p.Region] = int(p.Size)
+	}
+	return &warmPool{
+		key:      cfg.APIKey,
+		tag:      cfg.Tag + "-pool",
+		sizes:    sizes,
+		renderer: renderer,
+		lock:     &sync.Mutex{},
+		owned:    make(map[string]bool),
+		sshKeys:  sshKeys,
+		refreshC: make(chan struct{}, 1),
+	}
+}
+
+// requestRefresh schedules replenishing of the pool, without waiting for it
+func (p *warmPool) requestRefresh() {
+	select {
+	case p.refreshC <- struct{}{}:
+	default:
+	}
+}
+
+func (p *warmPool) run(ctx context.Context) {
+	ticker := time.NewTicker(poolRefreshInterval)
+	defer ticker.Stop()
+	p.replenish(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.refreshC:
+		}
+		p.replenish(ctx)
+	}
+}
+
+func (p *warmPool) instances(ctx context.Context, c *vultrClient) ([]govultr.Instance, error) {
+	return c.getVultrInstances(ctx, []string{p.tag})
+}
+
+// replenish replaces instances the pool can't use, and creates missing ones
+func (p *warmPool) replenish(ctx context.Context) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	c := newVultrClient(ctx, p.key)
+	reused, err := p.ensureSSHKey(ctx, c)
+	if err != nil {
+		log.Printf("warm pool: %v", err)
+		return
+	}
+
+	instances, err := p.instances(ctx, c)
+	if err != nil {
+		log.Printf("warm pool: %v", err)
+		return
+	}
+	counts := make(map[string]int)
+	for _, instance := range instances {
+		// instances of the pool are created with its ssh key, so the ones left by a previous run are reachable
+		if reused {
+			p.owned[instance.ID] = true
+		}
+		if !p.owned[instance.ID] {
+			log.Printf("warm pool: deleting instance %s, left by a previous run", instance.Label)
+			if err := c.client.Instance.Delete(ctx, instance.ID); err != nil {
+				log.Printf("warm pool: unable to delete instance %s: %v", instance.Label, err)
+			}
+			continue
+		}
+		counts[instance.Region]++
+	}
+
+	for city, size := range p.sizes {
+		region, err := c.getVultrRegionByCity(ctx, city)
+		if err != nil {
+			log.Printf("warm pool: %v", err)
+			continue
+		}
+		for missing := size - counts[region.ID]; missing > 0; missing-- {
+			if err := p.create(ctx, c, region); err != nil {
+				log.Printf("warm pool: unable to create instance in %s: %v", region.City, err)
+				break
+			}
+		}
+	}
+
+	if status, err := p.status(ctx, c); err == nil {
+		for _, s := range status {
+			log.Printf("warm pool: %s has %d/%d idle instances, costing $%.3f/hour", s.Region, s.Idle, s.Size, s.HourlyCost)
+		}
+	}
+}
+
+// ensureSSHKey reuses the stored ssh key of the pool, or generates a new one, and removes keys left by previous
+// runs. True is reported, when the stored key of a previous run is reused
+func (p *warmPool) ensureSSHKey(ctx context.Context, c *vultrClient) (bool, error) {
+	if p.sshKeyID != "" {
+		return false, nil
+	}
+	sshKeys, err := c.listSSHKeys(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	stored, err := p.sshKeys.GetByName(ctx, p.tag)
+	if err != nil {
+		return false, fmt.Errorf("unable to get the stored ssh key: %w", err)
+	}
+	if stored != nil {
+		for _, key := range sshKeys {
+			if key.ID == stored.ProviderID {
+				p.privateKey = stored.PrivateKey
+				p.sshKeyID = stored.ProviderID
+				return true, nil
+			}
+		}
+	}
+
+	for _, key := range sshKeys {
+		if key.Name == p.tag {
+			if err := c.client.SSHKey.Delete(ctx, key.ID); err != nil {
+				log.Printf("warm pool: unable to delete ssh key: %v", err)
+			}
+		}
+	}
+
+	privateKey, err := util.GeneratePrivateKey(1024)
+	if err != nil {
+		return false, fmt.Errorf("unable to generate ssh key: %w", err)
+	}
+	publicKey, err := util.GeneratePublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return false, fmt.Errorf("unable to generate ssh public key: %w", err)
+	}
+	sshKey, err := c.client.SSHKey.Create(ctx, &govultr.SSHKeyReq{Name: p.tag, SSHKey: string(publicKey)})
+	if err != nil {
+		return false, fmt.Errorf("unable to create ssh key: %w", err)
+	}
+	encoded := string(util.EncodePrivateKeyToPEM(privateKey))
+	err = p.sshKeys.Store(ctx, &sshkey.SSHKey{Name: p.tag, ProviderID: sshKey.ID, PrivateKey: encoded})
+	if err != nil {
+		if err := c.client.SSHKey.Delete(ctx, sshKey.ID); err != nil {
+			log.Printf("warm pool: unable to delete ssh key: %v", err)
+		}
+		return false, fmt.Errorf("unable to store ssh key: %w", err)
+	}
+	p.privateKey = encoded
+	p.sshKeyID = sshKey.ID
+	return false, nil
+}
+
+// create bootstraps an idle instance. Instances booted from a snapshot of the region are ready without bootstrap
+func (p *warmPool) create(ctx context.Context, c *vultrClient, region govultr.Region) error {
+	options := govultr.InstanceCreateReq{
+		Region:  region.ID,
+		Label:   poolLabelPrefix + util.CreateFunnyName(),
+		Tags:    []string{p.tag},
+		SSHKeys: []string{p.sshKeyID},
+	}
+	if snapshotID := p.snapshotFor(ctx, region.City); snapshotID != "" {
+		options.SnapshotID = snapshotID
+	} else {
+		script, err := p.renderer.RenderHost()
+		if err != nil {
+			return err
+		}
+		osID, err := c.getVultrOsID(ctx, "Ubuntu 20.04 LTS x64")
+		if err != nil {
+			return err
+		}
+		options.OsID = osID
+		options.UserData = base64.StdEncoding.EncodeToString([]byte(script))
+	}
+	instance, err := c.createInstance(ctx, options)
+	if err != nil {
+		return err
+	}
+	p.owned[instance.ID] = true
+	return nil
+}
+
+// claim starts the server on an idle instance of the region, and moves the instance out of the pool. False is
+// returned, when the region has no instance ready to be claimed
+func (p *warmPool) claim(ctx context.Context, c *vultrClient, server *nsserver.NSServer, regionID string, tags []string) (bool, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	instances, err := p.instances(ctx, c)
+	if err != nil {
+		return false, err
+	}
+	for _, instance := range instances {
+		if instance.Region != regionID || !p.owned[instance.ID] || instance.Status != activeStatus || instance.ServerStatus != "ok" {
+			continue
+		}
+
+		server.MainIP = instance.MainIP
+		server.SSHPrivateKey = p.privateKey
+		server.CreatedAt = time.Now()
+		data, err := util.NewStartupData(ctx, server, serverDescription, server.Insecure)
+		if err != nil {
+			return false, fmt.Errorf("failed to generate formatted script: %w", err)
+		}
+		script, err := p.renderer.RenderServer(data)
+		if err != nil {
+			return false, fmt.Errorf("failed to generate formatted script: %w", err)
+		}
+		if err := startDetached(server.MainIP, server.SSHPrivateKey, server.Container(), script); err != nil {
+			log.Printf("warm pool: unable to start server on %s, trying another instance: %v", instance.Label, err)
+			continue
+		}
+
+		_, err = c.client.Instance.Update(ctx, instance.ID, &govultr.InstanceUpdateReq{Label: server.HostName(), Tags: tags})
+		if err != nil {
+			return false, fmt.Errorf("unable to claim instance %s: %w", instance.Label, err)
+		}
+		delete(p.owned, instance.ID)
+		p.requestRefresh()
+		return true, nil
+	}
+	return false, nil
+}
+
+// status reports idle instances, and their cost by region
+func (p *warmPool) status(ctx context.Context, c *vultrClient) ([]util.PoolStatus, error) {
+	if p.hourlyCosts == nil {
+		plans, _, err := c.client.Plan.List(ctx, "", &govultr.ListOptions{PerPage: 500})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list plans: %w", err)
+		}
+		p.hourlyCosts = make(map[string]float64, len(plans))
+		for _, plan := range plans {
+			p.hourlyCosts[plan.ID] = float64(plan.MonthlyCost) / billedHoursPerMonth
+		}
+	}
+
+	instances, err := p.instances(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	regions, err := c.listVultrRegion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byCity := make(map[string]*util.PoolStatus, len(p.sizes))
+	for city, size := range p.sizes {
+		for _, region := range regions {
+			if strings.Contains(strings.ToLower(region.City), strings.ToLower(city)) {
+				byCity[region.ID] = &util.PoolStatus{Region: region.City, Size: size}
+				break
+			}
+		}
+	}
+	for _, instance := range instances {
+		s, ok := byCity[instance.Region]
+		if !ok || !p.owned[instance.ID] {
+			continue
+		}
+		s.Idle++
+		s.HourlyCost += p.hourlyCosts[instance.Plan]
+	}
+
+	status := make([]util.PoolStatus, 0, len(byCity))
+	for _, s := range byCity {
+		status = append(status, *s)
+	}
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Region < status[j].Region
+	})
+	return status, nil
+}
@@ -11,10 +11,13 @@ import (
 	"log"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/l1ghthouse/northstar-bootstrap/src/image"
 	"github.com/l1ghthouse/northstar-bootstrap/src/nsserver"
 	"github.com/l1ghthouse/northstar-bootstrap/src/providers/util"
+	"github.com/l1ghthouse/northstar-bootstrap/src/sshkey"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/oauth2"
 )
@@ -23,12 +26,40 @@ type Config struct {
 	APIKey   string `required:"true"`
 	Tag      string `default:"ephemeral"`
 	LogLimit uint   `default:"7340032"`
+	Startup  util.StartupConfig
+	// ServersPerInstance packs up to this many servers on a single instance, with offset ports. Bare metal servers
+	// always get their own instance
+	ServersPerInstance uint `default:"1"`
+	// Pool keeps bootstrapped instances idle, so servers created in their region start faster
+	Pool []PoolConfig
 }
 
+// vultrPlatform describes the Ubuntu 20.04 image, that instances are created with. Its cloud-init runs user data,
+// while images without cloud-init only run the boot script
+var vultrPlatform = util.Platform{PackageManager: util.PackageManagerApt, CloudInit: true}
+
+const serverDescription = "Northstar bot managed by https://github.com/l1ghthouse/northstar-bot"
+
 type Vultr struct {
 	key      string
 	Tags     []string
 	LogLimit uint
+	startup  *util.StartupRenderer
+	// repo tracks which host every server runs on
+	repo               nsserver.Repo
+	serversPerInstance int
+	// hostLock prevents deleting a host, while a server is placed on it
+	hostLock *sync.Mutex
+	// pending are servers placed on hosts, that are not stored yet, so their slots aren't picked again. Guarded by
+	// hostLock
+	pending map[string]*nsserver.NSServer
+	pool    *warmPool
+	// images are snapshots with the host bootstrap baked in, that servers boot from
+	images image.Repo
+	// builds are regions with an image build in progress, so a region is only built once at a time. Guarded by
+	// buildLock
+	builds    map[string]bool
+	buildLock *sync.Mutex
 }
 
 func (v Vultr) CreateServer(ctx context.Context, server *nsserver.NSServer) error {
@@ -38,26 +69,146 @@ func (v Vultr) CreateServer(ctx context.Context, server *nsserver.NSServer) erro
 		return err
 	}
 	server.Region = region.City
-	err = vClient.createNorthstarInstance(ctx, server, region.ID, v.Tags)
+
+	if v.serversPerInstance > 1 && !server.BareMetal {
+		placed, err := v.placeOnHost(ctx, vClient, server)
+		if err != nil {
+			return err
+		}
+		if placed {
+			return nil
+		}
+		server.Host = util.HostPrefix + server.Name
+	}
+
+	if v.pool != nil && !server.BareMetal {
+		claimed, err := v.pool.claim(ctx, vClient, server, region.ID, v.Tags)
+		if err != nil {
+			return err
+		}
+		if claimed {
+			return nil
+		}
+	}
+
+	err = vClient.createNorthstarInstance(ctx, server, region.ID, v.Tags, v.startup, v.snapshotFor(ctx, region.City))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// placeOnHost starts the server on a running host in the same region, that has a free slot. False is returned,
+// when there is no such host
+func (v Vultr) placeOnHost(ctx context.Context, c *vultrClient, server *nsserver.NSServer) (bool, error) {
+	v.hostLock.Lock()
+	defer v.hostLock.Unlock()
+
+	servers, err := v.hostedServers(ctx)
+	if err != nil {
+		return false, err
+	}
+	host, slot, ok := util.PickHost(servers, server.Region, v.serversPerInstance, server.GameUDPPort)
+	if !ok {
+		return false, nil
+	}
+	instance, err := c.getVultrInstanceByName(ctx, host.Name, v.Tags)
+	if err != nil || instance.Status != activeStatus {
+		log.Printf("host %s can't be used, creating a new one: %v", host.Name, err)
+		return false, nil
+	}
+
+	shared := host.Servers[0]
+	server.Host = host.Name
+	server.MainIP = shared.MainIP
+	server.SSHPrivateKey = shared.SSHPrivateKey
+	server.GameUDPPort += slot
+	server.AuthTCPPort += slot
+	server.ContainerName = util.SlotContainerName(slot)
+	server.CreatedAt = time.Now()
+
+	data, err := util.NewStartupData(ctx, server, serverDescription, server.Insecure)
+	if err != nil {
+		return false, fmt.Errorf("failed to generate formatted script: %w", err)
+	}
+	script, err := v.startup.RenderServer(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to generate formatted script: %w", err)
+	}
+	reserved := *server
+	v.pending[server.Name] = &reserved
+	if err := startDetached(server.MainIP, server.SSHPrivateKey, server.Container(), script); err != nil {
+		delete(v.pending, server.Name)
+		return false, fmt.Errorf("unable to start server on host %s: %w", host.Name, err)
+	}
+	return true, nil
+}
+
+// pendingServerTTL is how long a placed server keeps its slot reserved, before it has to be stored
+const pendingServerTTL = 10 * time.Minute
+
+// hostedServers returns stored servers, and servers placed on hosts, that are not stored yet. hostLock has to be held
+func (v Vultr) hostedServers(ctx context.Context) ([]*nsserver.NSServer, error) {
+	servers, err := v.repo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list servers: %w", err)
+	}
+	stored := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		stored[server.Name] = true
+	}
+	for name, server := range v.pending {
+		if stored[name] || time.Since(server.CreatedAt) > pendingServerTTL {
+			delete(v.pending, name)
+			continue
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
 func (v Vultr) RestartServer(ctx context.Context, server *nsserver.NSServer) error {
 	c := newVultrClient(ctx, v.key)
 
-	return c.restartNorthstarInstance(ctx, server.Name, server.SSHPrivateKey, v.Tags, server.BareMetal)
+	return c.restartNorthstarInstance(ctx, server.HostName(), server.SSHPrivateKey, v.Tags, server.BareMetal, server.Container())
 }
 
 func (v Vultr) DeleteServer(ctx context.Context, server *nsserver.NSServer) error {
 	c := newVultrClient(ctx, v.key)
 
-	err := c.deleteNorthstarInstance(ctx, server.Name, v.Tags)
+	// servers missing from the database are only known by name, while their instance can be labelled as a host
+	fallbackHost := util.HostPrefix + server.Name
+	hostsOthers := false
+
+	// the host is only deleted with its last server
+	if v.repo != nil {
+		v.hostLock.Lock()
+		defer v.hostLock.Unlock()
+
+		servers, err := v.hostedServers(ctx)
+		if err != nil {
+			return err
+		}
+		for _, other := range servers {
+			if other.Name == server.Name {
+				continue
+			}
+			if server.Host != "" && other.HostName() == server.HostName() {
+				return removeFromHost(server)
+			}
+			hostsOthers = hostsOthers || other.HostName() == fallbackHost
+		}
+	}
+
+	err := c.deleteNorthstarInstance(ctx, server.HostName(), v.Tags)
+	if err != nil && server.Host == "" && !hostsOthers {
+		if hostErr := c.deleteNorthstarInstance(ctx, fallbackHost, v.Tags); hostErr == nil {
+			return nil
+		}
+	}
 	var err2 error
 	if err != nil {
-		err2 = c.deleteBareMetalInstance(ctx, server.Name, v.Tags)
+		err2 = c.deleteBareMetalInstance(ctx, server.HostName(), v.Tags)
 		if err2 != nil {
 			return fmt.Errorf("failed to delete server: Error during instance delete:%v.\n Error during bare metal server delete:%v", err, err2)
 		}
@@ -81,6 +232,15 @@ func (v Vultr) GetRunningServers(ctx context.Context) ([]*nsserver.NSServer, err
 		return nil, err
 	}
 
+	var hosts map[string]*util.Host
+	if v.repo != nil {
+		cachedServers, err := v.repo.GetAll(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list servers: %w", err)
+		}
+		hosts = util.GroupByHost(cachedServers)
+	}
+
 	var ns []*nsserver.NSServer
 
 	for _, instance := range instances {
@@ -91,6 +251,20 @@ func (v Vultr) GetRunningServers(ctx context.Context) ([]*nsserver.NSServer, err
 					return nil, fmt.Errorf("failed to parse date: %w", err)
 				}
 
+				// instances are listed by their servers. Instances without known servers are listed by their label
+				if host, ok := hosts[instance.Label]; ok {
+					for _, server := range host.Servers {
+						ns = append(ns, &nsserver.NSServer{
+							Name:          server.Name,
+							Region:        region.City,
+							CreatedAt:     server.CreatedAt,
+							Host:          server.Host,
+							ContainerName: server.ContainerName,
+						})
+					}
+					continue
+				}
+
 				ns = append(ns, &nsserver.NSServer{
 					Name:      instance.Label,
 					Region:    region.City,
@@ -124,11 +298,62 @@ func (v Vultr) GetRunningServers(ctx context.Context) ([]*nsserver.NSServer, err
 func (v Vultr) ExtractServerLogs(ctx context.Context, server *nsserver.NSServer) (*bytes.Buffer, error) {
 	vClient := newVultrClient(ctx, v.key)
 
-	return vClient.extractServerLogs(ctx, server.Name, server.SSHPrivateKey, v.Tags, v.LogLimit, server.BareMetal)
+	return vClient.extractServerLogs(ctx, server.HostName(), server.SSHPrivateKey, v.Tags, v.LogLimit, server.BareMetal, server.Container())
 }
 
-func NewVultrProvider(cfg Config) (*Vultr, error) {
-	return &Vultr{key: cfg.APIKey, Tags: []string{cfg.Tag}, LogLimit: cfg.LogLimit}, nil
+func (v Vultr) ListRegions(ctx context.Context) ([]string, error) {
+	vClient := newVultrClient(ctx, v.key)
+	regions, err := vClient.listVultrRegion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cities := make([]string, len(regions))
+	for i, region := range regions {
+		cities[i] = region.City
+	}
+	return cities, nil
+}
+
+func NewVultrProvider(cfg Config, repo nsserver.Repo, images image.Repo, sshKeys sshkey.Repo) (*Vultr, error) {
+	startup, err := util.NewStartupRenderer(cfg.Startup, vultrPlatform)
+	if err != nil {
+		return nil, err
+	}
+	serversPerInstance := int(cfg.ServersPerInstance)
+	if serversPerInstance < 1 {
+		serversPerInstance = 1
+	}
+	pool := newWarmPool(cfg, startup, sshKeys)
+	v := &Vultr{
+		key:                cfg.APIKey,
+		Tags:               []string{cfg.Tag},
+		LogLimit:           cfg.LogLimit,
+		startup:            startup,
+		repo:               repo,
+		serversPerInstance: serversPerInstance,
+		hostLock:           &sync.Mutex{},
+		pending:            make(map[string]*nsserver.NSServer),
+		pool:               pool,
+		images:             images,
+		builds:             make(map[string]bool),
+		buildLock:          &sync.Mutex{},
+	}
+	if pool != nil {
+		pool.snapshotFor = v.snapshotFor
+		go pool.run(context.Background())
+	}
+	return v, nil
+}
+
+// WarmPoolStatus reports idle instances of the pool, and their cost
+func (v Vultr) WarmPoolStatus(ctx context.Context) ([]util.PoolStatus, error) {
+	if v.pool == nil {
+		return nil, nil
+	}
+	v.pool.lock.Lock()
+	defer v.pool.lock.Unlock()
+	return v.pool.status(ctx, newVultrClient(ctx, v.key))
 }
 
 func client(ctx context.Context, key string) *govultr.Client {
@@ -186,7 +411,7 @@ func generateSSHClient(mainIP, sshPrivateKey string) (*ssh.Client, error) {
 	return nil, fmt.Errorf("unable to connect to vultr instance: %w", err)
 }
 
-func (v *vultrClient) extractServerLogs(ctx context.Context, serverName string, sshPrivateKey string, tags []string, logLimit uint, bareMetal bool) (*bytes.Buffer, error) {
+func (v *vultrClient) extractServerLogs(ctx context.Context, serverName string, sshPrivateKey string, tags []string, logLimit uint, bareMetal bool, container string) (*bytes.Buffer, error) {
 	var status string
 	var mainIP string
 	if bareMetal {
@@ -224,7 +449,7 @@ func (v *vultrClient) extractServerLogs(ctx context.Context, serverName string,
 	if err != nil {
 		return nil, fmt.Errorf("unable to create ssh session: %w", err)
 	}
-	output, err := sshSession.CombinedOutput(util.FormatLogExtractionScript())
+	output, err := sshSession.CombinedOutput(util.FormatLogExtractionScript(container))
 	if err != nil {
 		return nil, fmt.Errorf("unable to extract logs: %w, output: %s", err, string(output))
 	}
@@ -320,27 +545,47 @@ var errTimedOutToReceivePublicIP = errors.New("timed out to receive public IP")
 var vultrPlans = []string{"vc2-4c-8gb", "vhp-4c-8gb-intel", "vhp-4c-8gb-amd"}
 var bareMetalPlans = []string{"vbm-4c-32gb", "vbm-6c-32gb"}
 
-func (v *vultrClient) createNorthstarInstance(ctx context.Context, server *nsserver.NSServer, regionID string, tags []string) error {
+// createNorthstarInstance creates the instance of the server. Instances booted from snapshotID only start the server,
+// since the host bootstrap is baked into the snapshot. Bare metal servers don't boot from snapshots
+func (v *vultrClient) createNorthstarInstance(ctx context.Context, server *nsserver.NSServer, regionID string, tags []string, startup *util.StartupRenderer, snapshotID string) error {
 	// Create a base64 encoded script that will: Download northstar container, and Titanfall2 files from git, to startup the server
 
-	s, err := util.FormatStartupScript(ctx, server, "Northstar bot managed by https://github.com/l1ghthouse/northstar-bot", server.Insecure)
+	data, err := util.NewStartupData(ctx, server, serverDescription, server.Insecure)
+	if err != nil {
+		return fmt.Errorf("failed to generate formatted script: %w", err)
+	}
+	if server.BareMetal {
+		snapshotID = ""
+	}
+	// user data is rendered in the configured format, while the boot script is always a bash script, for images
+	// without cloud-init. The bootstrap only proceeds on the first of them to run
+	var userData, bootScript string
+	if snapshotID != "" {
+		userData, err = startup.RenderPrebaked(data)
+		bootScript = userData
+	} else {
+		userData, err = startup.Render(data)
+		if err == nil {
+			bootScript, err = startup.RenderScript(data)
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("failed to generate formatted script: %w", err)
 	}
 
-	cmd := base64.StdEncoding.EncodeToString([]byte(s))
+	cmd := base64.StdEncoding.EncodeToString([]byte(userData))
 
 	script := &govultr.StartupScriptReq{
-		Name:   server.Name,
+		Name:   server.HostName(),
 		Type:   "boot",
-		Script: cmd,
+		Script: base64.StdEncoding.EncodeToString([]byte(bootScript)),
 	}
 
-	// Docker image doesn't have cloud-init, so we will instead create a custom script first
 	resScript, err := v.client.StartupScript.Create(ctx, script)
 	if err != nil {
 		return fmt.Errorf("unable to create startup script: %w", err)
 	}
+	scriptID := resScript.ID
 
 	key, err := util.GeneratePrivateKey(1024)
 	if err != nil {
@@ -353,7 +598,7 @@ func (v *vultrClient) createNorthstarInstance(ctx context.Context, server *nsser
 	}
 
 	sshKeyReq := govultr.SSHKeyReq{
-		Name:   server.Name,
+		Name:   server.HostName(),
 		SSHKey: string(publicKey),
 	}
 
@@ -435,11 +680,11 @@ func (v *vultrClient) createNorthstarInstance(ctx context.Context, server *nsser
 			instanceOptions := &govultr.BareMetalCreate{
 				Region:          regionID,
 				Plan:            plan, // One of low-end bare metal server plans
-				Label:           server.Name,
+				Label:           server.HostName(),
 				OsID:            ubuntuDockerOsID,
-				UserData:        cmd,          // Command to pull docker container, and create a server
-				StartupScriptID: resScript.ID, // Startup script
-				Tags:            tags,         // ephemeral is used to autodelete the instance after some time
+				UserData:        cmd,      // Command to pull docker container, and create a server
+				StartupScriptID: scriptID, // Startup script
+				Tags:            tags,     // ephemeral is used to autodelete the instance after some time
 				SSHKeyIDs:       []string{sshKey.ID},
 			}
 
@@ -456,27 +701,22 @@ func (v *vultrClient) createNorthstarInstance(ctx context.Context, server *nsser
 		dateCreated = bareMetalInstance.DateCreated
 
 	} else {
-		var instance *govultr.Instance
-		for _, plan := range vultrPlans {
-			instanceOptions := &govultr.InstanceCreateReq{
-				Region:   regionID,
-				Plan:     plan, // One of: 4cpu, 8gb plan until single core is supported. More info: https://www.vultr.com/api/#operation/list-os
-				Label:    server.Name,
-				OsID:     ubuntuDockerOsID,
-				UserData: cmd,          // Command to pull docker container, and create a server
-				ScriptID: resScript.ID, // Startup script
-				Tags:     tags,         // ephemeral is used to autodelete the instance after some time
-				SSHKeys:  []string{sshKey.ID},
-			}
-
-			instance, err = v.client.Instance.Create(ctx, instanceOptions)
-			if err == nil {
-				break
-			}
+		options := govultr.InstanceCreateReq{
+			Region:   regionID,
+			Label:    server.HostName(),
+			OsID:     ubuntuDockerOsID,
+			UserData: cmd,      // Command to pull docker container, and create a server
+			ScriptID: scriptID, // Startup script
+			Tags:     tags,     // ephemeral is used to autodelete the instance after some time
+			SSHKeys:  []string{sshKey.ID},
 		}
-
+		if snapshotID != "" {
+			options.OsID = 0
+			options.SnapshotID = snapshotID
+		}
+		instance, err := v.createInstance(ctx, options)
 		if err != nil {
-			return fmt.Errorf("unable to create instance: %w", err)
+			return err
 		}
 
 		dateCreated = instance.DateCreated
@@ -507,7 +747,7 @@ func (v *vultrClient) createNorthstarInstance(ctx context.Context, server *nsser
 		case <-ticker.C:
 			var mainIP string
 			if server.BareMetal {
-				bareMetalInstance, err := v.getBareMetalByName(ctx, server.Name, tags)
+				bareMetalInstance, err := v.getBareMetalByName(ctx, server.HostName(), tags)
 				if err != nil {
 					if strings.Contains(err.Error(), "no instance found for") {
 						continue
@@ -517,7 +757,7 @@ func (v *vultrClient) createNorthstarInstance(ctx context.Context, server *nsser
 				mainIP = bareMetalInstance.MainIP
 
 			} else {
-				instance, err := v.getVultrInstanceByName(ctx, server.Name, tags)
+				instance, err := v.getVultrInstanceByName(ctx, server.HostName(), tags)
 				if err != nil {
 					if strings.Contains(err.Error(), "no instance found for") {
 						continue
@@ -537,6 +777,21 @@ func (v *vultrClient) createNorthstarInstance(ctx context.Context, server *nsser
 
 }
 
+// createInstance tries the plans in order, until one of them can be created in the region
+func (v *vultrClient) createInstance(ctx context.Context, options govultr.InstanceCreateReq) (*govultr.Instance, error) {
+	var err error
+	for _, plan := range vultrPlans {
+		// One of: 4cpu, 8gb plan until single core is supported. More info: https://www.vultr.com/api/#operation/list-os
+		options.Plan = plan
+		instance, createErr := v.client.Instance.Create(ctx, &options)
+		if createErr == nil {
+			return instance, nil
+		}
+		err = createErr
+	}
+	return nil, fmt.Errorf("unable to create instance: %w", err)
+}
+
 func (v *vultrClient) getVultrOsID(ctx context.Context, name string) (int, error) {
 	list, _, err := v.client.OS.List(ctx, &govultr.ListOptions{})
 	if err != nil {
@@ -583,7 +838,7 @@ func (v *vultrClient) listSSHKeys(ctx context.Context) ([]govultr.SSHKey, error)
 	return sshKeys, nil
 }
 
-func (v *vultrClient) restartNorthstarInstance(ctx context.Context, serverName string, sshPrivateKey string, tags []string, isBareMetal bool) error {
+func (v *vultrClient) restartNorthstarInstance(ctx context.Context, serverName string, sshPrivateKey string, tags []string, isBareMetal bool, container string) error {
 	var status string
 	var mainIP string
 	if isBareMetal {
@@ -622,7 +877,7 @@ func (v *vultrClient) restartNorthstarInstance(ctx context.Context, serverName s
 	if err != nil {
 		return fmt.Errorf("unable to create ssh session: %w", err)
 	}
-	err = sshSession.Run(util.RestartServerScript())
+	err = sshSession.Run(util.RestartServerScript(container))
 	if err != nil {
 		return fmt.Errorf("unable to restart the server: %w", err)
 	}
@@ -713,3 +968,53 @@ func (v *vultrClient) deleteSSHKey(ctx context.Context, serverName string) error
 
 	return nil
 }
+
+// startDetached copies the script to the host, and runs it in the background, so the ssh session doesn't have to
+// wait for the host to be bootstrapped
+func startDetached(mainIP string, sshPrivateKey string, name string, script string) error {
+	sshClient, err := generateSSHClient(mainIP, sshPrivateKey)
+	if err != nil {
+		return err
+	}
+	defer func(sshClient *ssh.Client) {
+		err := sshClient.Close()
+		if err != nil {
+			log.Printf("failed to close ssh client: %v", err)
+		}
+	}(sshClient)
+
+	sshSession, err := sshClient.NewSession()
+	if err != nil {
+		return fmt.Errorf("unable to create ssh session: %w", err)
+	}
+	sshSession.Stdin = strings.NewReader(script)
+	output, err := sshSession.CombinedOutput(fmt.Sprintf("cat > /root/%s.sh && setsid nohup bash /root/%s.sh > /root/%s.log 2>&1 < /dev/null &", name, name, name))
+	if err != nil {
+		return fmt.Errorf("unable to start script: %w, output: %s", err, string(output))
+	}
+	return nil
+}
+
+// removeFromHost removes the server container, leaving the host, and its other servers running
+func removeFromHost(server *nsserver.NSServer) error {
+	sshClient, err := generateSSHClient(server.MainIP, server.SSHPrivateKey)
+	if err != nil {
+		return err
+	}
+	defer func(sshClient *ssh.Client) {
+		err := sshClient.Close()
+		if err != nil {
+			log.Printf("failed to close ssh client: %v", err)
+		}
+	}(sshClient)
+
+	sshSession, err := sshClient.NewSession()
+	if err != nil {
+		return fmt.Errorf("unable to create ssh session: %w", err)
+	}
+	output, err := sshSession.CombinedOutput(util.RemoveServerScript(server.Container()))
+	if err != nil {
+		return fmt.Errorf("unable to remove server from host %s: %w, output: %s", server.HostName(), err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package sshkey
+
+import (
+	"context"
+)
+
+type Repo interface {
+	// GetByName returns nil, when there is no key with the name
+	GetByName(ctx context.Context, name string) (*SSHKey, error)
+	// Store creates the key, or replaces an existing key with the same name
+	Store(ctx context.Context, k *SSHKey) error
+}
@@ -0,0 +1,31 @@
+package sshkey
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// SSHKey is a provider ssh key, that is reused after restarts, so instances created with it stay reachable
+type SSHKey struct {
+	ID   uuid.UUID `json:"id,omitempty" gorm:"type:uuid;primary_key;"`
+	Name string    `json:"name" gorm:"not null;default:null;uniqueIndex"`
+	// ProviderID is the id of the public key registered with the provider
+	ProviderID string `json:"providerID" gorm:"not null;default:null"`
+	PrivateKey string `json:"privateKey" gorm:"not null;default:null"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (k *SSHKey) BeforeCreate(tx *gorm.DB) (err error) {
+	if k.ID == uuid.Nil {
+		u, err := uuid.NewV4()
+		if err != nil {
+			return fmt.Errorf("failed to create uuid: %w", err)
+		}
+		k.ID = u
+	}
+	return nil
+}
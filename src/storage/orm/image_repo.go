@@ -0,0 +1,46 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/image"
+	"gorm.io/gorm"
+)
+
+type imageRepo struct {
+	db *gorm.DB
+}
+
+func NewImageRepo(db *gorm.DB) image.Repo {
+	return &imageRepo{db}
+}
+
+func (h *imageRepo) GetAll(ctx context.Context) ([]*image.Image, error) {
+	images := make([]*image.Image, 0)
+	err := h.db.WithContext(ctx).Order("region").Find(&images).Error
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (h *imageRepo) Store(ctx context.Context, i *image.Image) error {
+	existing := &image.Image{}
+	err := h.db.WithContext(ctx).Where("region = ?", i.Region).First(existing).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return h.db.WithContext(ctx).Create(i).Error
+	case err != nil:
+		return fmt.Errorf("error looking up image of region: %s, err: %w", i.Region, err)
+	}
+
+	i.ID = existing.ID
+	i.CreatedAt = existing.CreatedAt
+	err = h.db.WithContext(ctx).Save(i).Error
+	if err != nil {
+		return fmt.Errorf("error updating image of region: %s, err: %w", i.Region, err)
+	}
+	return nil
+}
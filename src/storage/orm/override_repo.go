@@ -0,0 +1,57 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/override"
+	"gorm.io/gorm"
+)
+
+type overrideRepo struct {
+	db *gorm.DB
+}
+
+func NewOverrideRepo(db *gorm.DB) override.Repo {
+	return &overrideRepo{db}
+}
+
+func (h *overrideRepo) GetAll(ctx context.Context) ([]*override.Override, error) {
+	overrides := make([]*override.Override, 0)
+	err := h.db.WithContext(ctx).Order("guild_id, command, flag").Find(&overrides).Error
+	if err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+func (h *overrideRepo) Store(ctx context.Context, o *override.Override) error {
+	existing := &override.Override{}
+	err := h.db.WithContext(ctx).Where("guild_id = ? AND command = ? AND flag = ?", o.GuildID, o.Command, o.Flag).First(existing).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return h.db.WithContext(ctx).Create(o).Error
+	case err != nil:
+		return fmt.Errorf("error looking up override for %s %s, err: %w", o.Command, o.Flag, err)
+	}
+
+	o.ID = existing.ID
+	o.CreatedAt = existing.CreatedAt
+	err = h.db.WithContext(ctx).Save(o).Error
+	if err != nil {
+		return fmt.Errorf("error updating override for %s %s, err: %w", o.Command, o.Flag, err)
+	}
+	return nil
+}
+
+func (h *overrideRepo) Delete(ctx context.Context, guildID string, command string, flag string) error {
+	result := h.db.WithContext(ctx).Delete(&override.Override{}, "guild_id = ? AND command = ? AND flag = ?", guildID, command, flag)
+	if result.Error != nil {
+		return fmt.Errorf("error deleting override for %s %s, err: %w", command, flag, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoRowsAffected
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/preset"
+	"gorm.io/gorm"
+)
+
+type presetRepo struct {
+	db *gorm.DB
+}
+
+func NewPresetRepo(db *gorm.DB) preset.Repo {
+	return &presetRepo{db}
+}
+
+func (h *presetRepo) Store(ctx context.Context, p *preset.Preset) error {
+	existing := &preset.Preset{}
+	err := h.db.WithContext(ctx).Where("guild_id = ? AND user_id = ? AND name = ?", p.GuildID, p.UserID, p.Name).First(existing).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return h.db.WithContext(ctx).Create(p).Error
+	case err != nil:
+		return fmt.Errorf("error looking up preset with name: %s, err: %w", p.Name, err)
+	}
+
+	p.ID = existing.ID
+	p.CreatedAt = existing.CreatedAt
+	err = h.db.WithContext(ctx).Save(p).Error
+	if err != nil {
+		return fmt.Errorf("error updating preset with name: %s, err: %w", p.Name, err)
+	}
+	return nil
+}
+
+func (h *presetRepo) GetByName(ctx context.Context, guildID string, userID string, name string) (*preset.Preset, error) {
+	presets := make([]*preset.Preset, 0)
+	err := h.db.WithContext(ctx).Where("guild_id = ? AND (user_id = ? OR user_id = '') AND name = ?", guildID, userID, name).Find(&presets).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(presets) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	for _, p := range presets {
+		if p.UserID != "" {
+			return p, nil
+		}
+	}
+	return presets[0], nil
+}
+
+func (h *presetRepo) List(ctx context.Context, guildID string, userID string) ([]*preset.Preset, error) {
+	presets := make([]*preset.Preset, 0)
+	err := h.db.WithContext(ctx).Where("guild_id = ? AND (user_id = ? OR user_id = '')", guildID, userID).Order("name").Find(&presets).Error
+	if err != nil {
+		return nil, err
+	}
+	return presets, nil
+}
+
+func (h *presetRepo) DeleteByName(ctx context.Context, guildID string, userID string, name string) error {
+	result := h.db.WithContext(ctx).Delete(&preset.Preset{}, "guild_id = ? AND user_id = ? AND name = ?", guildID, userID, name)
+	if result.Error != nil {
+		return fmt.Errorf("error deleting preset with name: %s, err: %w", name, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoRowsAffected
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/l1ghthouse/northstar-bootstrap/src/sshkey"
+	"gorm.io/gorm"
+)
+
+type sshKeyRepo struct {
+	db *gorm.DB
+}
+
+func NewSSHKeyRepo(db *gorm.DB) sshkey.Repo {
+	return &sshKeyRepo{db}
+}
+
+func (h *sshKeyRepo) GetByName(ctx context.Context, name string) (*sshkey.SSHKey, error) {
+	key := &sshkey.SSHKey{}
+	err := h.db.WithContext(ctx).Where("name = ?", name).First(key).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return key, nil
+}
+
+func (h *sshKeyRepo) Store(ctx context.Context, k *sshkey.SSHKey) error {
+	existing := &sshkey.SSHKey{}
+	err := h.db.WithContext(ctx).Where("name = ?", k.Name).First(existing).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return h.db.WithContext(ctx).Create(k).Error
+	case err != nil:
+		return fmt.Errorf("error looking up ssh key: %s, err: %w", k.Name, err)
+	}
+
+	k.ID = existing.ID
+	k.CreatedAt = existing.CreatedAt
+	err = h.db.WithContext(ctx).Save(k).Error
+	if err != nil {
+		return fmt.Errorf("error updating ssh key: %s, err: %w", k.Name, err)
+	}
+	return nil
+}